@@ -0,0 +1,240 @@
+// Package client is the supported Go API for driving a gitpool daemon over
+// its unix socket, or with Open the pool on disk without a daemon:
+//
+//	c, err := client.NewDefault()
+//	...
+//	wt, err := c.Claim(ctx, "my-app", "fix-the-thing", client.ClaimOptions{Owner: "ci"})
+//	if errors.Is(err, client.ErrPoolAtCapacity) {
+//		// retry later
+//	}
+//	...
+//	err = c.Release(ctx, wt.WorktreeID)
+//
+// Every method honours its context: when ctx is cancelled or its deadline
+// passes, the method returns ctx.Err(). The daemon may still complete a
+// request whose answer was abandoned, e.g. a claim, which then has to be
+// found with List and released. All other failures are *Error values with a
+// stable Code.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// Client talks to a daemon, or with Open serves requests in-process
+type Client struct {
+	ipc   *ipc.Client
+	local *local
+}
+
+// New returns a client for the daemon listening on socketPath
+func New(socketPath string) *Client {
+	return &Client{ipc: ipc.NewClient(socketPath)}
+}
+
+// NewDefault returns a client for the daemon gp would talk to, honouring
+// GITPOOL_SOCKET_PATH and socket_path in config.yaml
+func NewDefault() (*Client, error) {
+	cfg, err := config.LoadWithCustomPaths("", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return New(cfg.SocketPath), nil
+}
+
+// SetTimeout bounds every request to a daemon, on top of its context's
+// deadline. Zero, the default, means no limit; claims can take a while on
+// large repositories. It has no effect on a client from Open.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	if c.ipc != nil {
+		c.ipc.Timeout = timeout
+	}
+}
+
+type ClaimOptions struct {
+	// Owner is recorded in the claim history
+	Owner string
+}
+
+// Claim checks out a new branch in an idle worktree of repo
+func (c *Client) Claim(ctx context.Context, repo, branch string, opts ClaimOptions) (*Claimed, error) {
+	var claimed Claimed
+	err := c.call(ctx, ipc.MessageTypeClaim, ipc.ClaimRequest{
+		RepoName: repo,
+		Branch:   branch,
+		Owner:    opts.Owner,
+	}, &claimed)
+	if err != nil {
+		return nil, err
+	}
+	return &claimed, nil
+}
+
+// Release resets a claimed worktree and returns it to the pool
+func (c *Client) Release(ctx context.Context, worktreeID string) error {
+	return c.call(ctx, ipc.MessageTypeRelease, ipc.ReleaseRequest{WorktreeID: worktreeID}, nil)
+}
+
+// List returns all worktrees of all tracked repositories
+func (c *Client) List(ctx context.Context) ([]Worktree, error) {
+	var details []*models.WorktreeDetail
+	if err := c.call(ctx, ipc.MessageTypeWorktreeList, nil, &details); err != nil {
+		return nil, err
+	}
+
+	worktrees := make([]Worktree, 0, len(details))
+	for _, d := range details {
+		worktrees = append(worktrees, newWorktree(d))
+	}
+	return worktrees, nil
+}
+
+// Show returns one worktree
+func (c *Client) Show(ctx context.Context, worktreeID string) (*Worktree, error) {
+	var detail models.WorktreeDetail
+	if err := c.call(ctx, ipc.MessageTypeShow, ipc.ShowRequest{WorktreeID: worktreeID}, &detail); err != nil {
+		return nil, err
+	}
+	wt := newWorktree(&detail)
+	return &wt, nil
+}
+
+// Repositories returns the tracked repositories
+func (c *Client) Repositories(ctx context.Context) ([]Repository, error) {
+	var repos []*models.Repository
+	if err := c.call(ctx, ipc.MessageTypeRepoList, nil, &repos); err != nil {
+		return nil, err
+	}
+
+	result := make([]Repository, 0, len(repos))
+	for _, r := range repos {
+		result = append(result, newRepository(r))
+	}
+	return result, nil
+}
+
+type TrackOptions struct {
+	// MaxWorktrees is the pool size; zero uses the default of 8, and negative
+	// values fail with CodeInvalidArgument
+	MaxWorktrees int
+	// BaseBranch is detected from the repository when empty
+	BaseBranch string
+}
+
+// Track starts pooling worktrees of the git repository at path, which must
+// be an absolute path on the daemon's host
+func (c *Client) Track(ctx context.Context, name, path string, opts TrackOptions) (*Repository, error) {
+	var repo models.Repository
+	err := c.call(ctx, ipc.MessageTypeRepoAdd, ipc.RepoAddRequest{
+		Name:         name,
+		Path:         path,
+		MaxWorktrees: opts.MaxWorktrees,
+		BaseBranch:   opts.BaseBranch,
+	}, &repo)
+	if err != nil {
+		return nil, err
+	}
+	r := newRepository(&repo)
+	return &r, nil
+}
+
+// Untrack deletes the worktrees of repo and stops tracking it. It fails with
+// ErrWorktreeInUse while any of them is claimed.
+func (c *Client) Untrack(ctx context.Context, repo string) error {
+	return c.call(ctx, ipc.MessageTypeRepoRemove, repo, nil)
+}
+
+// Refresh fetches repo and moves its idle worktrees to the latest base branch
+func (c *Client) Refresh(ctx context.Context, repo string) (*Refreshed, error) {
+	var result struct {
+		Repository       string `json:"repository"`
+		WorktreesUpdated int    `json:"worktrees_updated"`
+		WorktreesCleaned int    `json:"worktrees_cleaned"`
+	}
+	if err := c.call(ctx, ipc.MessageTypeRefresh, ipc.RefreshRequest{RepoName: repo}, &result); err != nil {
+		return nil, err
+	}
+	return &Refreshed{
+		Repo:             result.Repository,
+		WorktreesUpdated: result.WorktreesUpdated,
+		WorktreesCleaned: result.WorktreesCleaned,
+	}, nil
+}
+
+// Status describes the daemon and the pool of repo, or of every repository
+// if repo is empty
+func (c *Client) Status(ctx context.Context, repo string) (*Status, error) {
+	var status Status
+	if err := c.call(ctx, ipc.MessageTypeDaemonStatus, nil, &status); err != nil {
+		return nil, err
+	}
+
+	var pools []*models.PoolStatus
+	if err := c.call(ctx, ipc.MessageTypePoolStatus, ipc.PoolStatusRequest{RepoName: repo}, &pools); err != nil {
+		return nil, err
+	}
+	status.Pools = make([]PoolStatus, 0, len(pools))
+	for _, p := range pools {
+		status.Pools = append(status.Pools, newPoolStatus(p))
+	}
+	return &status, nil
+}
+
+// call sends a request and decodes the response data into out, if not nil
+func (c *Client) call(ctx context.Context, msgType ipc.MessageType, req interface{}, out interface{}) error {
+	resp, err := c.send(ctx, msgType, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return wrapError(err)
+	}
+	if !resp.Success {
+		code := resp.Code
+		if code == "" {
+			code = CodeInternal
+		}
+		return &Error{Code: code, Message: resp.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+	// Data holds generic JSON values from a daemon and models in-process;
+	// either way it round-trips through JSON into out
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return wrapError(fmt.Errorf("failed to parse response: %w", err))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return wrapError(fmt.Errorf("failed to parse response: %w", err))
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, msgType ipc.MessageType, req interface{}) (*ipc.Response, error) {
+	if c.local != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp := c.local.handle(msgType, req)
+		return &resp, nil
+	}
+
+	msg := ipc.Message{Type: msgType}
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		msg.Data = data
+	}
+	return c.ipc.SendMessageContext(ctx, msg)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// fakeHandler implements the handlers the tests call; any other one panics
+type fakeHandler struct {
+	ipc.Handler
+	block chan struct{}
+}
+
+func (h *fakeHandler) HandleClaim(req ipc.ClaimRequest) ipc.Response {
+	if req.Branch == "slow" {
+		<-h.block
+	}
+	if req.Branch == "taken" {
+		return ipc.ErrorResponse(errcode.New(errcode.BranchInUse, "branch '%s' is already in use", req.Branch))
+	}
+	return ipc.Response{Success: true, Data: ipc.ClaimResponse{WorktreeID: "wt-1", Path: "/pool/wt-1"}}
+}
+
+func (h *fakeHandler) HandleShow(req ipc.ShowRequest) ipc.Response {
+	branch := "feature"
+	leasedAt := time.Now()
+	return ipc.Response{Success: true, Data: models.WorktreeDetail{
+		Worktree: &models.Worktree{
+			Name:     req.WorktreeID,
+			Path:     "/pool/" + req.WorktreeID,
+			Status:   models.WorktreeStatusInUse,
+			Branch:   &branch,
+			LeasedAt: &leasedAt,
+		},
+		Repository: &models.Repository{Name: "my-app"},
+	}}
+}
+
+func startServer(t *testing.T) (*Client, *fakeHandler) {
+	t.Helper()
+	handler := &fakeHandler{block: make(chan struct{})}
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	server, err := ipc.NewServer(socket, handler, ipc.SocketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	t.Cleanup(func() {
+		close(handler.block)
+		server.Close()
+	})
+	return New(socket), handler
+}
+
+func TestClient(t *testing.T) {
+	c, _ := startServer(t)
+	ctx := context.Background()
+
+	claimed, err := c.Claim(ctx, "my-app", "feature", ClaimOptions{Owner: "ci"})
+	if err != nil || claimed.WorktreeID != "wt-1" || claimed.Path != "/pool/wt-1" {
+		t.Fatalf("Claim() = %+v, %v", claimed, err)
+	}
+
+	wt, err := c.Show(ctx, "wt-1")
+	if err != nil || wt.ID != "wt-1" || wt.Repo != "my-app" || wt.Status != WorktreeInUse || wt.Branch != "feature" || wt.ClaimedAt == nil {
+		t.Fatalf("Show() = %+v, %v", wt, err)
+	}
+
+	_, err = c.Claim(ctx, "my-app", "taken", ClaimOptions{})
+	var clientErr *Error
+	if !errors.Is(err, ErrBranchInUse) || !errors.As(err, &clientErr) || clientErr.Code != CodeBranchInUse {
+		t.Errorf("Claim() of a taken branch error = %v, want ErrBranchInUse", err)
+	}
+	if errors.Is(err, ErrPoolAtCapacity) {
+		t.Errorf("error %v matches ErrPoolAtCapacity", err)
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	c, _ := startServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Claim(ctx, "my-app", "slow", ClaimOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Claim() past the deadline error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestClientDaemonUnavailable(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "missing.sock"))
+	if _, err := c.List(context.Background()); !errors.Is(err, ErrDaemonUnavailable) || CodeOf(err) != CodeDaemonUnavailable {
+		t.Errorf("List() without a daemon error = %v, want ErrDaemonUnavailable", err)
+	}
+}
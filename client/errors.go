@@ -0,0 +1,84 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+)
+
+// Code classifies an error. Codes are stable; see docs/errors.md.
+type Code = errcode.Code
+
+const (
+	CodeInternal          = errcode.Internal
+	CodeInvalidArgument   = errcode.InvalidArgument
+	CodeDaemonUnavailable = errcode.DaemonUnavailable
+	CodeRepoNotFound      = errcode.RepoNotFound
+	CodeWorktreeNotFound  = errcode.WorktreeNotFound
+	CodeRepoExists        = errcode.RepoExists
+	CodeBranchInUse       = errcode.BranchInUse
+	CodePoolAtCapacity    = errcode.PoolAtCapacity
+	CodeWorktreeInUse     = errcode.WorktreeInUse
+	CodeWorktreeCorrupt   = errcode.WorktreeCorrupt
+	CodeConflict          = errcode.Conflict
+	CodeProtocolMismatch  = errcode.ProtocolMismatch
+	CodeShuttingDown      = errcode.ShuttingDown
+	CodePermissionDenied  = errcode.PermissionDenied
+	CodeQuotaExceeded     = errcode.QuotaExceeded
+)
+
+// Sentinels for errors.Is; they match any *Error with the same code
+var (
+	ErrDaemonUnavailable = &Error{Code: CodeDaemonUnavailable}
+	ErrRepoNotFound      = &Error{Code: CodeRepoNotFound}
+	ErrWorktreeNotFound  = &Error{Code: CodeWorktreeNotFound}
+	ErrRepoExists        = &Error{Code: CodeRepoExists}
+	ErrBranchInUse       = &Error{Code: CodeBranchInUse}
+	ErrPoolAtCapacity    = &Error{Code: CodePoolAtCapacity}
+	ErrWorktreeInUse     = &Error{Code: CodeWorktreeInUse}
+	ErrProtocolMismatch  = &Error{Code: CodeProtocolMismatch}
+	ErrShuttingDown      = &Error{Code: CodeShuttingDown}
+	ErrPermissionDenied  = &Error{Code: CodePermissionDenied}
+	ErrQuotaExceeded     = &Error{Code: CodeQuotaExceeded}
+)
+
+// Error is returned for every failure except an ended context, for which
+// the context's error is returned as is
+type Error struct {
+	Code    Code
+	Message string
+	err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is an *Error with the same code, so the
+// sentinels match
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// CodeOf returns the code of err, or CodeInternal if it has none
+func CodeOf(err error) Code {
+	return errcode.Of(err)
+}
+
+// wrapError turns a transport error into an *Error
+func wrapError(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Code: errcode.Of(err), Message: err.Error(), err: err}
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/albertywu/gitpool/internal/pool"
+	"github.com/albertywu/gitpool/internal/repo"
+	"github.com/albertywu/gitpool/internal/version"
+)
+
+// local serves requests in this process from the pool on disk
+type local struct {
+	dir      string
+	store    *db.Store
+	pool     *pool.Pool
+	repos    *repo.Manager
+	openedAt time.Time
+}
+
+// Open uses the pool in dataDir (the daemon's worktree directory, by default
+// ~/.gitpool/worktrees) directly from this process, without a daemon. Any
+// number of processes, and a daemon, can use the same directory at once:
+// changes to the pool are serialized through a lock file, and operations a
+// crashed process left unfinished are recovered when the next one opens it.
+//
+// Claims are held to the quotas, and run the hooks, of the config.yaml the
+// daemon would read: the one in $GITPOOL_CONFIG_DIR, or ~/.gitpool. Changes
+// to it apply to clients opened afterwards; Open fails if it is invalid.
+//
+// Requests run in the calling goroutine. Their context is checked before
+// they start, but once started, e.g. waiting for the lock or checking out a
+// branch, they run to completion. Nothing reconciles the pool in the
+// background; Refresh does that on demand. Events are not published to
+// 'gp events'. Logs go to slog.Default().
+//
+// Call Close when done.
+func Open(dataDir string) (*Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store, err := db.NewStoreWithPath(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	poolLock := lockfile.NewMutex(pool.LockFile(dataDir))
+	l := &local{
+		dir:      dataDir,
+		store:    store,
+		pool:     pool.NewPool(store, nil, dataDir, poolLock),
+		repos:    repo.NewManager(store, nil, poolLock),
+		openedAt: time.Now(),
+	}
+	l.pool.SetQuotas(pool.QuotasFromConfig(cfg.Quotas))
+	l.pool.SetHooks(pool.HooksFromConfig(cfg.Hooks))
+
+	if _, err := l.pool.Recover(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to recover interrupted operations: %w", err)
+	}
+
+	return &Client{local: l}, nil
+}
+
+// Close releases the database of a client from Open. It does nothing for a
+// daemon client.
+func (c *Client) Close() error {
+	if c.local == nil {
+		return nil
+	}
+	return c.local.store.Close()
+}
+
+// handle serves a request the way the daemon would
+func (l *local) handle(msgType ipc.MessageType, req interface{}) ipc.Response {
+	switch msgType {
+	case ipc.MessageTypeClaim:
+		r := req.(ipc.ClaimRequest)
+		wt, err := l.pool.ClaimWorktree(r.RepoName, r.Branch, pool.ClaimOptions{Owner: r.Owner, OwnerUID: os.Getuid()})
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Data: ipc.ClaimResponse{WorktreeID: wt.Name, Path: wt.Path}}
+
+	case ipc.MessageTypeRelease:
+		if err := l.pool.ReleaseWorktree(req.(ipc.ReleaseRequest).WorktreeID, pool.ReleaseOptions{}); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true}
+
+	case ipc.MessageTypeWorktreeList:
+		details, err := l.store.ListAllWorktreesWithRepos()
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Data: details}
+
+	case ipc.MessageTypeShow:
+		id := req.(ipc.ShowRequest).WorktreeID
+		wt, err := l.store.GetWorktreeByName(id)
+		if err != nil {
+			if wt, err = l.store.GetWorktree(id); err != nil {
+				return ipc.ErrorResponse(errcode.New(errcode.WorktreeNotFound, "worktree '%s' not found", id))
+			}
+		}
+		repository, err := l.store.GetRepositoryByID(wt.RepoID)
+		if err != nil {
+			return ipc.ErrorResponse(errcode.New(errcode.RepoNotFound, "repository not found for worktree"))
+		}
+		return ipc.Response{Success: true, Data: models.WorktreeDetail{Worktree: wt, Repository: repository}}
+
+	case ipc.MessageTypeRepoList:
+		repos, err := l.repos.ListRepositories()
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Data: repos}
+
+	case ipc.MessageTypeRepoAdd:
+		r := req.(ipc.RepoAddRequest)
+		repository, err := l.repos.AddRepository(r.Name, r.Path, r.BaseBranch, r.MaxWorktrees)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		l.pool.CreateInitialWorktrees(repository, repository.MaxWorktrees)
+		return ipc.Response{Success: true, Data: repository}
+
+	case ipc.MessageTypeRepoRemove:
+		if err := l.repos.RemoveRepository(req.(string)); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true}
+
+	case ipc.MessageTypeRefresh:
+		name := req.(ipc.RefreshRequest).RepoName
+		repository, err := l.store.GetRepository(name)
+		if err != nil {
+			return ipc.ErrorResponse(errcode.New(errcode.RepoNotFound, "repository '%s' not found", name))
+		}
+		run, err := l.pool.ReconcileWorktrees(repository)
+		if err != nil {
+			return ipc.ErrorResponse(fmt.Errorf("refresh failed: %w", err))
+		}
+		l.store.UpdateRepositoryLastFetch(repository.Name, time.Now())
+		return ipc.Response{Success: true, Data: map[string]interface{}{
+			"repository":        repository.Name,
+			"worktrees_updated": run.Created,
+			"worktrees_cleaned": run.Cleaned,
+		}}
+
+	case ipc.MessageTypeDaemonStatus:
+		// There is no daemon; describe this process instead
+		return ipc.Response{Success: true, Data: map[string]interface{}{
+			"version":      version.Version,
+			"pid":          os.Getpid(),
+			"started_at":   l.openedAt,
+			"worktree_dir": l.dir,
+		}}
+
+	case ipc.MessageTypePoolStatus:
+		statuses, err := l.pool.GetPoolStatus(req.(ipc.PoolStatusRequest).RepoName)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Data: statuses}
+	}
+
+	return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "'%s' is not supported in-process", msgType))
+}
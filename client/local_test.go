@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+func createRepo(t *testing.T, path string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", path},
+		{"-C", path, "-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"-C", path, "remote", "add", "origin", path},
+		{"-C", path, "fetch", "-q", "origin"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+// TestOpenSharesPool opens the same directory twice, which lock each other
+// out like separate processes would, since flock locks belong to the open
+// file
+func TestOpenSharesPool(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GITPOOL_CONFIG_DIR", dir)
+	repoPath := filepath.Join(dir, "repo")
+	createRepo(t, repoPath)
+	dataDir := filepath.Join(dir, "data")
+	ctx := context.Background()
+
+	a, err := Open(dataDir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer a.Close()
+	b, err := Open(dataDir)
+	if err != nil {
+		t.Fatalf("second Open() error = %v", err)
+	}
+	defer b.Close()
+
+	if _, err := a.Track(ctx, "app", repoPath, TrackOptions{MaxWorktrees: 2, BaseBranch: "main"}); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	// Both clients race for the same branch; exactly one gets it
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	for i, c := range []*Client{a, b} {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			_, results[i] = c.Claim(ctx, "app", "feature", ClaimOptions{})
+		}(i, c)
+	}
+	wg.Wait()
+	if (results[0] == nil) == (results[1] == nil) {
+		t.Fatalf("Claim() results = %v, want exactly one success", results)
+	}
+	for _, err := range results {
+		if err != nil && !errors.Is(err, ErrBranchInUse) {
+			t.Errorf("losing Claim() error = %v, want ErrBranchInUse", err)
+		}
+	}
+
+	second, err := b.Claim(ctx, "app", "other", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := a.Claim(ctx, "app", "third", ClaimOptions{}); !errors.Is(err, ErrPoolAtCapacity) {
+		t.Errorf("Claim() of a full pool error = %v, want ErrPoolAtCapacity", err)
+	}
+
+	// A claim interrupted by a crash is rolled back by the next Open
+	wt, err := a.local.store.GetWorktreeByName(second.WorktreeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.local.store.TransitionWorktree(wt.ID.String(), models.WorktreeStatusInUse, models.WorktreeStatusClaiming, wt.LeasedAt, wt.Branch); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(dataDir)
+	if err != nil {
+		t.Fatalf("third Open() error = %v", err)
+	}
+	defer c.Close()
+	shown, err := c.Show(ctx, second.WorktreeID)
+	if err != nil || shown.Status != WorktreeIdle {
+		t.Errorf("Show() after recovery = %+v, %v, want idle", shown, err)
+	}
+}
+
+func TestTrackMaxWorktrees(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GITPOOL_CONFIG_DIR", dir)
+	repoPath := filepath.Join(dir, "repo")
+	createRepo(t, repoPath)
+	ctx := context.Background()
+
+	c, err := Open(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Track(ctx, "negative", repoPath, TrackOptions{MaxWorktrees: -1}); !errors.Is(err, &Error{Code: CodeInvalidArgument}) {
+		t.Errorf("Track() with MaxWorktrees -1 error = %v, want invalid_argument", err)
+	}
+
+	repo, err := c.Track(ctx, "app", repoPath, TrackOptions{BaseBranch: "main"})
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if repo.MaxWorktrees != 8 {
+		t.Errorf("Track() MaxWorktrees = %d, want the default of 8", repo.MaxWorktrees)
+	}
+	worktrees, err := c.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(worktrees) != 8 {
+		t.Errorf("List() returned %d worktrees, want 8", len(worktrees))
+	}
+}
+
+func TestOpenEnforcesQuotas(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GITPOOL_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("quotas:\n  per_user: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repoPath := filepath.Join(dir, "repo")
+	createRepo(t, repoPath)
+	ctx := context.Background()
+
+	c, err := Open(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Track(ctx, "app", repoPath, TrackOptions{MaxWorktrees: 2, BaseBranch: "main"}); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if _, err := c.Claim(ctx, "app", "first", ClaimOptions{}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := c.Claim(ctx, "app", "second", ClaimOptions{}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Claim() over the quota error = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestOpenRejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GITPOOL_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("quotas:\n  per_user: -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := Open(filepath.Join(dir, "data")); err == nil {
+		c.Close()
+		t.Error("Open() with negative quotas succeeded, want error")
+	}
+}
+
+func TestUntrackPrunesSourceWorktrees(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GITPOOL_CONFIG_DIR", dir)
+	repoPath := filepath.Join(dir, "repo")
+	createRepo(t, repoPath)
+	ctx := context.Background()
+
+	c, err := Open(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Track(ctx, "app", repoPath, TrackOptions{MaxWorktrees: 2, BaseBranch: "main"}); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	wt, err := c.Claim(ctx, "app", "feature", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := c.Release(ctx, wt.WorktreeID); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := c.Untrack(ctx, "app"); err != nil {
+		t.Fatalf("Untrack() error = %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", repoPath, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		t.Fatalf("git worktree list: %v", err)
+	}
+	if n := strings.Count(string(out), "worktree "); n != 1 {
+		t.Errorf("source repository lists %d worktrees after untrack, want only its own:\n%s", n, out)
+	}
+}
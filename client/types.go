@@ -0,0 +1,147 @@
+package client
+
+import (
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+type WorktreeStatus string
+
+const (
+	WorktreeIdle    WorktreeStatus = WorktreeStatus(models.WorktreeStatusIdle)
+	WorktreeInUse   WorktreeStatus = WorktreeStatus(models.WorktreeStatusInUse)
+	WorktreeCorrupt WorktreeStatus = WorktreeStatus(models.WorktreeStatusCorrupt)
+	// Transitional statuses, seen while the daemon is creating, claiming,
+	// releasing or deleting a worktree
+	WorktreeCreating  WorktreeStatus = WorktreeStatus(models.WorktreeStatusCreating)
+	WorktreeClaiming  WorktreeStatus = WorktreeStatus(models.WorktreeStatusClaiming)
+	WorktreeReleasing WorktreeStatus = WorktreeStatus(models.WorktreeStatusReleasing)
+	WorktreeDeleting  WorktreeStatus = WorktreeStatus(models.WorktreeStatusDeleting)
+)
+
+type Repository struct {
+	Name         string     `json:"name"`
+	Path         string     `json:"path"`
+	MaxWorktrees int        `json:"max_worktrees"`
+	BaseBranch   string     `json:"base_branch"`
+	LastFetch    *time.Time `json:"last_fetch,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type Worktree struct {
+	// ID identifies the worktree to Show and Release
+	ID     string         `json:"id"`
+	Repo   string         `json:"repo"`
+	Path   string         `json:"path"`
+	Status WorktreeStatus `json:"status"`
+	// Branch, ClaimedAt and OwnerUID are set while the worktree is claimed;
+	// OwnerUID is the user that claimed it, if known
+	Branch    string     `json:"branch,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	OwnerUID  *int       `json:"owner_uid,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Claimed is a worktree checked out on a new branch by Claim
+type Claimed struct {
+	WorktreeID string `json:"worktree_id"`
+	Path       string `json:"path"`
+}
+
+// Status describes the daemon and the pools of the repositories asked for
+type Status struct {
+	Version        string       `json:"version"`
+	PID            int          `json:"pid"`
+	StartedAt      time.Time    `json:"started_at"`
+	SocketPath     string       `json:"socket_path"`
+	HTTPListen     string       `json:"http_listen,omitempty"`
+	WorktreeDir    string       `json:"worktree_dir"`
+	LastReconciler *time.Time   `json:"last_reconciler"`
+	Pools          []PoolStatus `json:"pools"`
+	// Quotas is the usage of each configured claim quota by each user and
+	// owner label holding claims
+	Quotas []QuotaUsage `json:"quotas"`
+}
+
+// QuotaUsage is how many claims a user (Scope "user", UID set) or owner
+// label (Scope "label", Label set) holds against a quota, in Repo or, if
+// empty, in all repositories
+type QuotaUsage struct {
+	Scope string `json:"scope"`
+	UID   *int   `json:"uid,omitempty"`
+	Label string `json:"label,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Used  int    `json:"used"`
+	Limit int    `json:"limit"`
+}
+
+type PoolStatus struct {
+	Repo       string     `json:"repo"`
+	Total      int        `json:"total"`
+	Idle       int        `json:"idle"`
+	InUse      int        `json:"in_use"`
+	Corrupt    int        `json:"corrupt"`
+	Max        int        `json:"max"`
+	BaseBranch string     `json:"base_branch"`
+	BaseSHA    string     `json:"base_sha"`
+	LastFetch  *time.Time `json:"last_fetch,omitempty"`
+	// StaleIdle counts idle worktrees behind BaseSHA; MaxBehind is the most
+	// commits any of them is behind
+	StaleIdle int `json:"stale_idle"`
+	MaxBehind int `json:"max_commits_behind"`
+}
+
+// Refreshed reports what Refresh did
+type Refreshed struct {
+	Repo             string `json:"repo"`
+	WorktreesUpdated int    `json:"worktrees_updated"`
+	WorktreesCleaned int    `json:"worktrees_cleaned"`
+}
+
+func newRepository(r *models.Repository) Repository {
+	return Repository{
+		Name:         r.Name,
+		Path:         r.Path,
+		MaxWorktrees: r.MaxWorktrees,
+		BaseBranch:   r.BaseBranch,
+		LastFetch:    r.LastFetchTime,
+		CreatedAt:    r.CreatedAt,
+	}
+}
+
+func newWorktree(d *models.WorktreeDetail) Worktree {
+	wt := Worktree{
+		ID:        d.Worktree.Name,
+		Path:      d.Worktree.Path,
+		Status:    WorktreeStatus(d.Worktree.Status),
+		CreatedAt: d.Worktree.CreatedAt,
+	}
+	if d.Repository != nil {
+		wt.Repo = d.Repository.Name
+	}
+	if d.Worktree.Status.HoldsBranch() {
+		if d.Worktree.Branch != nil {
+			wt.Branch = *d.Worktree.Branch
+		}
+		wt.ClaimedAt = d.Worktree.LeasedAt
+		wt.OwnerUID = d.Worktree.OwnerUID
+	}
+	return wt
+}
+
+func newPoolStatus(p *models.PoolStatus) PoolStatus {
+	return PoolStatus{
+		Repo:       p.RepoName,
+		Total:      p.Total,
+		Idle:       p.Idle,
+		InUse:      p.InUse,
+		Corrupt:    p.Corrupt,
+		Max:        p.Max,
+		BaseBranch: p.BaseBranch,
+		BaseSHA:    p.BaseSHA,
+		LastFetch:  p.LastFetch,
+		StaleIdle:  p.StaleIdle,
+		MaxBehind:  p.MaxBehind,
+	}
+}
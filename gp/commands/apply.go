@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/albertywu/gitpool/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyPrune  bool
+	applyDryRun bool
+)
+
+func NewApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Track, update or untrack repositories to match config.yaml",
+		Long: `Make the tracked repositories match the repos section of config.yaml.
+
+Declared repositories that aren't tracked are tracked (cloning url into path
+first if needed), and tracked ones whose max_worktrees, base_branch or
+fetch_interval differ are updated in place. Tracked repositories that aren't declared are only
+untracked with --prune.
+
+Example config.yaml:
+
+  repos:
+    my-app:
+      path: ~/src/my-app
+      max_worktrees: 4
+    api:
+      url: git@github.com:example/api.git
+      path: ~/src/api
+      base_branch: main
+      fetch_interval: 15m`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			declared, err := config.LoadRepoSpecs(cfg.ConfigDir)
+			if err != nil {
+				return err
+			}
+
+			client := newClient(cfg)
+			resp, err := client.RepoList()
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to list repositories")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var tracked []*models.Repository
+			if err := json.Unmarshal(data, &tracked); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			actions := repo.Plan(declared, tracked)
+			if len(actions) == 0 && !machineOutput() {
+				internal.PrintInfo("Tracked repositories match config.yaml")
+				return nil
+			}
+
+			// Table output reports each action as it happens, JSON and YAML
+			// output all of them at the end
+			results := make([]map[string]interface{}, 0, len(actions))
+			report := func(action repo.Action, result string, err error) {
+				changes := action.Changes
+				if changes == nil {
+					changes = []string{}
+				}
+				r := map[string]interface{}{
+					"action":  action.Kind,
+					"repo":    action.Name,
+					"changes": changes,
+					"result":  result,
+				}
+				if err != nil {
+					r["error"] = err.Error()
+				}
+				results = append(results, r)
+			}
+
+			failed := 0
+			for _, action := range actions {
+				summary := fmt.Sprintf("%s %s (%s)", action.Kind, action.Name, strings.Join(action.Changes, ", "))
+
+				switch {
+				case action.Kind == repo.ActionConflict:
+					warnf("Skipping %s", summary)
+					report(action, "skipped", nil)
+					continue
+				case action.Kind == repo.ActionUntrack && !applyPrune:
+					warnf("Not untracking %s: not declared in config (use --prune)", action.Name)
+					report(action, "skipped", nil)
+					continue
+				case applyDryRun:
+					if !machineOutput() {
+						fmt.Printf("Would %s\n", summary)
+					}
+					report(action, "planned", nil)
+					continue
+				}
+
+				if err := applyAction(client, action); err != nil {
+					internal.PrintError("Failed to %s: %v", summary, err)
+					report(action, "failed", err)
+					failed++
+					continue
+				}
+				if !machineOutput() {
+					internal.PrintInfo("Applied %s", summary)
+				}
+				report(action, "applied", nil)
+			}
+
+			if machineOutput() {
+				if err := printDocument(results); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d action(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&applyPrune, "prune", false, "Untrack repositories that are not declared in config.yaml")
+	cmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show what would change without changing anything")
+
+	return cmd
+}
+
+func applyAction(client *ipc.Client, action repo.Action) error {
+	var resp *ipc.Response
+	var err error
+
+	switch action.Kind {
+	case repo.ActionTrack:
+		if err := cloneIfMissing(action.Spec); err != nil {
+			return err
+		}
+		resp, err = client.RepoAdd(ipc.RepoAddRequest{
+			Name:         action.Name,
+			Path:         action.Spec.Path,
+			MaxWorktrees: action.Spec.MaxWorktrees,
+			BaseBranch:   action.Spec.BaseBranch,
+		})
+		// Tracking doesn't take a fetch interval, so set it right after
+		if err == nil && resp.Success && action.Spec.FetchInterval != 0 {
+			minutes := int(action.Spec.FetchInterval / time.Minute)
+			resp, err = client.RepoUpdate(ipc.RepoUpdateRequest{Name: action.Name, FetchInterval: &minutes})
+		}
+	case repo.ActionUpdate:
+		resp, err = client.RepoUpdate(ipc.RepoUpdateRequest{
+			Name:          action.Name,
+			MaxWorktrees:  action.Update.MaxWorktrees,
+			BaseBranch:    action.Update.BaseBranch,
+			FetchInterval: action.Update.FetchInterval,
+		})
+	case repo.ActionUntrack:
+		resp, err = client.RepoRemove(action.Name)
+	default:
+		return fmt.Errorf("unknown action %s", action.Kind)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to communicate with daemon: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
+
+// cloneIfMissing clones a declared repository's url to its path when there is
+// nothing there yet
+func cloneIfMissing(spec config.RepoSpec) error {
+	if _, err := os.Stat(spec.Path); err == nil || spec.URL == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(spec.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(spec.Path), err)
+	}
+
+	infof("Cloning %s to %s", spec.URL, spec.Path)
+	clone := exec.Command("git", "clone", spec.URL, spec.Path)
+	clone.Stdout = os.Stderr
+	clone.Stderr = os.Stderr
+	if err := clone.Run(); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", spec.URL, err)
+	}
+	return nil
+}
@@ -3,14 +3,18 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/user"
 	"strings"
 
-	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
 	"github.com/albertywu/gitpool/internal/ipc"
 	"github.com/spf13/cobra"
 )
 
+var claimOwner string
+
 func NewClaimCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "claim <repo-name> <branch-name>",
@@ -19,8 +23,10 @@ func NewClaimCmd() *cobra.Command {
 
 The branch name must be a valid git branch name and unique within the repository's worktrees.
 
-The command outputs JSON with the worktree ID and path to STDOUT.
-Error messages are printed to STDERR.
+The command outputs JSON with the worktree ID and path to STDOUT, unless
+--output asks for YAML or a table. Errors are printed to STDERR in the same
+format with a stable error code, which also determines the exit status (see
+docs/errors.md).
 
 Example:
   gp claim my-app feature-xyz
@@ -37,14 +43,15 @@ Usage with jq:
   
   # CD into the worktree
   cd $(gp claim my-app feature-xyz | jq -r .path)`,
-		Args: cobra.ExactArgs(2),
+		Args:        cobra.ExactArgs(2),
+		Annotations: map[string]string{"output": "json"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			repoName := args[0]
 			branch := args[1]
 
 			// Validate branch name
 			if err := validateBranchName(branch); err != nil {
-				return fmt.Errorf("invalid branch name: %w", err)
+				return errcode.Wrap(errcode.InvalidArgument, err, "invalid branch name")
 			}
 
 			cfg, err := config.LoadWithCustomPaths("", "", "")
@@ -52,10 +59,11 @@ Usage with jq:
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			req := ipc.ClaimRequest{
 				RepoName: repoName,
 				Branch:   branch,
+				Owner:    claimOwner,
 			}
 
 			resp, err := client.Claim(req)
@@ -64,8 +72,7 @@ Usage with jq:
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to claim worktree: %s", resp.Error)
-				return fmt.Errorf("claim failed")
+				return daemonError(resp, "failed to claim worktree")
 			}
 
 			// Parse the response to get both worktree ID and path
@@ -80,26 +87,34 @@ Usage with jq:
 				return fmt.Errorf("failed to parse response: %w", err)
 			}
 
-			// Create JSON output with worktree ID and path
-			output := map[string]string{
+			result := map[string]string{
 				"worktree_id": claimResp.WorktreeID,
 				"path":        claimResp.Path,
 			}
-
-			// Marshal and print JSON to STDOUT
-			jsonOutput, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to create JSON output: %w", err)
-			}
-			fmt.Println(string(jsonOutput))
-
-			return nil
+			return printResult(result, func() error {
+				fmt.Printf("Worktree ID: %s\n", claimResp.WorktreeID)
+				fmt.Printf("Path:        %s\n", claimResp.Path)
+				return nil
+			})
 		},
 	}
 
+	cmd.Flags().StringVar(&claimOwner, "owner", defaultOwner(), "Owner recorded in the claim history")
+
 	return cmd
 }
 
+// defaultOwner returns the current user's login name
+func defaultOwner() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
 // validateBranchName checks if a branch name is valid according to git rules
 func validateBranchName(branch string) error {
 	// Basic git branch name validation rules
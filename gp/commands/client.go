@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/daemon"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/lockfile"
+)
+
+// newClient returns a daemon client that starts a background daemon on
+// first use when autostart is enabled
+func newClient(cfg *config.Config) *ipc.Client {
+	client := ipc.NewClient(cfg.SocketPath)
+	if cfg.Autostart {
+		client.AutoStart = func() error {
+			return autostartDaemon(cfg)
+		}
+	}
+	return client
+}
+
+// autostartDaemon starts a background daemon unless another client beat us
+// to it. The lock serializes clients racing to start one.
+func autostartDaemon(cfg *config.Config) error {
+	lock, err := lockfile.Acquire(cfg.AutostartLockFile())
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// Whoever held the lock before us may have started it already
+	if daemon.CheckDaemonRunning(cfg.SocketPath) {
+		return nil
+	}
+
+	pid, err := spawnDaemon(cfg, cfg.LogFile())
+	if err != nil {
+		return err
+	}
+
+	// stdout may carry JSON output, so report on stderr
+	fmt.Fprintf(os.Stderr, "[INFO] Started gitpool daemon in background (pid %d)\n", pid)
+	return nil
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var dbMigrateStatus bool
+
+func NewDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect and maintain the gitpool database",
+	}
+
+	cmd.AddCommand(newDBMigrateCmd())
+
+	return cmd
+}
+
+func newDBMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database schema migrations",
+		Long: `Apply pending schema migrations to the gitpool database.
+
+The daemon applies pending migrations itself when it starts, so this is only
+needed to migrate ahead of time or to check the schema with --status. A
+database whose schema is newer than this gp knows is never touched; upgrade
+gp instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			store, err := db.OpenStoreWithPath(cfg.WorktreeDir)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if dbMigrateStatus {
+				return printMigrationStatus(store)
+			}
+
+			applied, err := store.Migrate()
+			if !machineOutput() {
+				for _, m := range applied {
+					internal.PrintInfo("Applied migration %d: %s", m.Version, m.Name)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			if applied == nil {
+				applied = []db.MigrationStatus{}
+			}
+			result := map[string]interface{}{
+				"schema_version": db.SchemaVersion(),
+				"applied":        applied,
+			}
+			return printResult(result, func() error {
+				if len(applied) == 0 {
+					internal.PrintInfo("Database schema is up to date (version %d)", db.SchemaVersion())
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dbMigrateStatus, "status", false, "List migrations and whether they are applied, without applying any")
+
+	return cmd
+}
+
+func printMigrationStatus(store *db.Store) error {
+	statuses, err := store.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, m := range statuses {
+		if m.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	err = printResult(statuses, func() error {
+		w := internal.NewTabWriter()
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED_AT")
+		for _, m := range statuses {
+			appliedAt := "pending"
+			if m.AppliedAt != nil {
+				appliedAt = m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, appliedAt)
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d pending migration(s); run 'gp db migrate' or start the daemon to apply them\n", pending)
+	}
+	return nil
+}
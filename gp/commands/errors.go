@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+// commandStarted is set once cobra has parsed the command line and a command
+// runs. Errors before that are usage errors: unknown commands or flags and
+// wrong argument counts.
+var commandStarted bool
+
+// MarkStarted is the root command's PersistentPreRunE. Usage is only worth
+// printing for usage errors, not for failures of a command that ran. It also
+// settles the command's output format.
+func MarkStarted(cmd *cobra.Command, args []string) error {
+	commandStarted = true
+	cmd.SilenceUsage = true
+
+	var err error
+	outputFormat, err = resolveOutput(cmd)
+	if !useColor() {
+		disableColors()
+	}
+	return err
+}
+
+// ReportError prints a command's error to stderr, as JSON or YAML if the
+// command writes those, and returns the process exit code for it
+func ReportError(cmd *cobra.Command, err error) int {
+	code := errcode.Of(err)
+	if !commandStarted {
+		code = errcode.InvalidArgument
+	}
+	exitCode := errcode.ExitCode(code)
+
+	format := outputFormat
+	if !commandStarted && cmd != nil {
+		// Usage errors happen before MarkStarted, but the flags are parsed
+		format, _ = resolveOutput(cmd)
+	}
+
+	report := map[string]interface{}{
+		"error":     err.Error(),
+		"code":      code,
+		"exit_code": exitCode,
+	}
+	switch format {
+	case outputJSON:
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Fprintln(os.Stderr, string(data))
+	case outputYAML:
+		data, _ := marshalYAML(report)
+		fmt.Fprint(os.Stderr, string(data))
+	default:
+		internal.PrintError("%v", err)
+	}
+
+	return exitCode
+}
+
+// daemonError turns a failed response into an error carrying the daemon's
+// error code
+func daemonError(resp *ipc.Response, action string) error {
+	return errcode.New(resp.Code, "%s: %s", action, resp.Error)
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventsRepo   string
+	eventsFollow bool
+)
+
+func NewEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show pool events",
+		Long: `Print recent pool events as newline-delimited JSON, one event per line.
+With --output yaml every event is a YAML document; with --output table events
+are printed as lines for people to read.
+
+Events are emitted when worktrees are created, claimed, released, corrupted or
+deleted, when repositories are tracked or untracked, and when a refresh starts
+or finishes. With --follow the command keeps running and prints new events as
+they happen.
+
+Example:
+  gp events --repo my-app --follow | jq -r 'select(.type == "worktree.released") | .worktree_id'`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{"output": "json"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := newClient(cfg)
+			req := ipc.SubscribeRequest{
+				RepoName: eventsRepo,
+				Follow:   eventsFollow,
+			}
+
+			out := cmd.OutOrStdout()
+			encoder := json.NewEncoder(out)
+			err = client.Subscribe(req, func(e events.Event) error {
+				switch outputFormat {
+				case outputYAML:
+					data, err := marshalYAML(e)
+					if err != nil {
+						return err
+					}
+					_, err = fmt.Fprintf(out, "---\n%s", data)
+					return err
+				case outputTable:
+					return printEvent(out, e)
+				}
+				return encoder.Encode(e)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to stream events: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&eventsRepo, "repo", "", "Only show events for this repository")
+	cmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "Keep streaming new events")
+
+	return cmd
+}
+
+// printEvent prints an event as a line of text. Streamed events can't be
+// aligned in columns.
+func printEvent(out io.Writer, e events.Event) error {
+	line := e.Time.Local().Format("2006-01-02 15:04:05") + " " + string(e.Type)
+	if e.Repo != "" {
+		line += " repo=" + e.Repo
+	}
+	if e.WorktreeID != "" {
+		line += " worktree=" + e.WorktreeID
+	}
+	if e.Branch != "" {
+		line += " branch=" + e.Branch
+	}
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += fmt.Sprintf(" %s=%v", k, e.Data[k])
+	}
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
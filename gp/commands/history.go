@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRepo     string
+	historyBranch   string
+	historySince    string
+	historyLimit    int
+	historyRejected bool
+	historyJSON     bool
+)
+
+func NewHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the claim history",
+		Long: `Show past and active worktree claims: who claimed which worktree, for which
+branch, for how long, and how the claim ended.
+
+With --rejected, claims that failed because the pool was at capacity are
+listed too, with outcome 'rejected' and no worktree.
+
+History older than claim_retention (default 30 days) is pruned by the daemon.
+
+Examples:
+  gp history --repo my-app --since 7d
+  gp history --branch feature-xyz --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := ipc.HistoryRequest{
+				RepoName: historyRepo,
+				Branch:   historyBranch,
+				Limit:    historyLimit,
+				Rejected: historyRejected,
+			}
+			if historySince != "" {
+				since, err := parseSince(historySince)
+				if err != nil {
+					return err
+				}
+				req.Since = &since
+			}
+
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := newClient(cfg)
+			resp, err := client.History(req)
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+
+			if !resp.Success {
+				return daemonError(resp, "failed to get claim history")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var claims []*models.Claim
+			if err := json.Unmarshal(data, &claims); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			output := make([]map[string]interface{}, 0, len(claims))
+			for _, c := range claims {
+				output = append(output, map[string]interface{}{
+					"worktree_id": c.WorktreeName,
+					"repo":        c.RepoName,
+					"branch":      c.Branch,
+					"owner":       c.Owner,
+					"claimed_at":  c.ClaimedAt,
+					"released_at": c.ReleasedAt,
+					"duration":    c.Duration().Round(time.Second).String(),
+					"start_sha":   c.StartSHA,
+					"end_sha":     c.EndSHA,
+					"outcome":     c.Outcome,
+				})
+			}
+
+			return printResult(output, func() error {
+				if len(claims) == 0 {
+					fmt.Println("No claims recorded")
+					return nil
+				}
+
+				w := internal.NewTabWriter()
+				fmt.Fprintln(w, "CLAIMED_AT\tREPO\tBRANCH\tOWNER\tDURATION\tOUTCOME\tWORKTREE")
+				for _, c := range claims {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+						internal.FormatTime(&c.ClaimedAt),
+						c.RepoName,
+						c.Branch,
+						c.Owner,
+						internal.FormatDuration(c.Duration()),
+						c.Outcome,
+						c.WorktreeName)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&historyRepo, "repo", "", "Only show claims for this repository")
+	cmd.Flags().StringVar(&historyBranch, "branch", "", "Only show claims for this branch")
+	cmd.Flags().StringVar(&historySince, "since", "", "Only show claims made since this time (e.g. 7d, 12h, 2006-01-02)")
+	cmd.Flags().IntVar(&historyLimit, "limit", 100, "Maximum number of claims to show (0 for all)")
+	cmd.Flags().BoolVar(&historyRejected, "rejected", false, "Also show claims rejected because the pool was at capacity")
+	cmd.Flags().BoolVar(&historyJSON, "json", false, "Output as JSON (same as --output json)")
+
+	return cmd
+}
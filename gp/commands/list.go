@@ -7,15 +7,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
-	"github.com/albertywu/gitpool/internal/ipc"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/spf13/cobra"
 )
 
-// ANSI color codes
-const (
+// ANSI color codes, cleared by disableColors
+var (
 	colorReset  = "\033[0m"
 	colorRed    = "\033[31m"
 	colorGreen  = "\033[32m"
@@ -27,6 +25,18 @@ const (
 	colorBold   = "\033[1m"
 )
 
+// hyperlinks is whether list links worktrees to their paths with OSC 8
+// terminal hyperlinks
+var hyperlinks = true
+
+// disableColors turns colors and hyperlinks off for output that isn't read
+// on a terminal
+func disableColors() {
+	colorReset, colorRed, colorGreen, colorYellow, colorBlue = "", "", "", "", ""
+	colorPurple, colorCyan, colorGray, colorBold = "", "", "", ""
+	hyperlinks = false
+}
+
 func NewListCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
@@ -38,15 +48,14 @@ func NewListCmd() *cobra.Command {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			resp, err := client.WorktreeList()
 			if err != nil {
 				return fmt.Errorf("failed to communicate with daemon: %w", err)
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to list worktrees: %s", resp.Error)
-				return fmt.Errorf("list worktrees failed")
+				return daemonError(resp, "failed to list worktrees")
 			}
 
 			// Parse response
@@ -56,6 +65,14 @@ func NewListCmd() *cobra.Command {
 				return fmt.Errorf("failed to parse response: %w", err)
 			}
 
+			if machineOutput() {
+				worktrees := make([]map[string]interface{}, 0, len(details))
+				for _, detail := range details {
+					worktrees = append(worktrees, worktreeOutput(detail))
+				}
+				return printDocument(worktrees)
+			}
+
 			if len(details) == 0 {
 				fmt.Println("No worktrees in pool")
 				return nil
@@ -208,11 +225,13 @@ func NewListCmd() *cobra.Command {
 					worktreeColor = colorGray
 				}
 
-				// Add terminal hyperlink to worktree path
+				// On a terminal, link the worktree to its path
 				// Format: OSC 8 ; params ; URI ST display_text OSC 8 ; ; ST
 				// OSC = \033]  ST = \033\\
-				terminalLink := fmt.Sprintf("\033]8;;file://%s\033\\%s%s%s\033]8;;\033\\",
-					wt.Path, worktreeColor, padRight(worktreeDisplay, worktreeWidth), colorReset)
+				terminalLink := fmt.Sprintf("%s%s%s", worktreeColor, padRight(worktreeDisplay, worktreeWidth), colorReset)
+				if hyperlinks {
+					terminalLink = fmt.Sprintf("\033]8;;file://%s\033\\%s\033]8;;\033\\", wt.Path, terminalLink)
+				}
 
 				// Format the row with fixed widths
 				fmt.Printf("%s%-*s%s  %s  %s%-*s%s  %s%-*s%s\n",
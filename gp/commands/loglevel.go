@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+func NewLogLevelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "log-level [debug|info|warn|error]",
+		Short: "Show or change the daemon's log level",
+		Long: `Show the running daemon's log level, or change it without a restart.
+
+The change lasts until the daemon stops; set log_level in config.yaml to make
+it permanent.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			var req ipc.LogLevelRequest
+			if len(args) == 1 {
+				req.Level = args[0]
+			}
+
+			client := newClient(cfg)
+			resp, err := client.LogLevel(req)
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to set log level")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var result struct {
+				Level string `json:"level"`
+			}
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			return printResult(result, func() error {
+				if req.Level != "" {
+					internal.PrintInfo("Log level set to %s", result.Level)
+				} else {
+					fmt.Println(result.Level)
+				}
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/logfile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsSince  string
+	logsFile   string
+)
+
+// logTimeLayout matches the timestamp prefix of logs written before
+// structured logging, with log.LstdFlags
+const logTimeLayout = "2006/01/02 15:04:05"
+
+func NewLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the daemon log",
+		Long: `Print the log of a daemon started with 'gp start --background'.
+
+With --since, rotated log files are included and only lines logged after the
+given time are printed. With --follow the command keeps printing new lines as
+they are written, across log rotations.
+
+Examples:
+  gp logs --since 1h
+  gp logs -f`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			path := logsFile
+			if path == "" {
+				path = cfg.LogFile()
+			}
+
+			var since *time.Time
+			if logsSince != "" {
+				t, err := parseSince(logsSince)
+				if err != nil {
+					return err
+				}
+				since = &t
+			}
+
+			out := cmd.OutOrStdout()
+
+			// Rotated files only matter when looking back in time
+			if since != nil {
+				for i := cfg.LogMaxBackups; i >= 1; i-- {
+					if err := printLogFile(out, logfile.BackupPath(path, i), since); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+				}
+			}
+
+			if err := printLogFile(out, path, since); err != nil {
+				if os.IsNotExist(err) && !logsFollow {
+					return fmt.Errorf("no daemon log at %s (is the daemon running with --background?)", path)
+				}
+				if !os.IsNotExist(err) {
+					return err
+				}
+			}
+
+			if logsFollow {
+				return followLogFile(out, path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new log lines")
+	cmd.Flags().StringVar(&logsSince, "since", "", "Only show lines logged since this time (e.g. 30m, 1d, 2006-01-02)")
+	cmd.Flags().StringVar(&logsFile, "log-file", "", "Read this log file instead of the default")
+
+	return cmd
+}
+
+// printLogFile copies a log file to out, skipping lines logged before since.
+// Lines without a timestamp (such as multi-line git output) belong to the
+// preceding entry.
+func printLogFile(out io.Writer, path string, since *time.Time) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	include := since == nil
+	for scanner.Scan() {
+		line := scanner.Text()
+		if since != nil {
+			if t, ok := parseLogTime(line); ok {
+				include = !t.Before(*since)
+			}
+		}
+		if include {
+			fmt.Fprintln(out, line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseLogTime extracts the timestamp of a text or JSON log record, or of a
+// line written by older versions using the standard log prefix
+func parseLogTime(line string) (time.Time, bool) {
+	switch {
+	case strings.HasPrefix(line, "time="):
+		value := strings.TrimPrefix(line, "time=")
+		if end := strings.IndexByte(value, ' '); end >= 0 {
+			value = value[:end]
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+
+	case strings.HasPrefix(line, `{"time":"`):
+		value := strings.TrimPrefix(line, `{"time":"`)
+		if end := strings.IndexByte(value, '"'); end >= 0 {
+			value = value[:end]
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+	}
+
+	if len(line) < len(logTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(logTimeLayout, line[:len(logTimeLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// followLogFile prints lines appended to path, reopening it when the daemon
+// rotates or recreates it
+func followLogFile(out io.Writer, path string) error {
+	var file *os.File
+	var offset int64
+
+	// Start following from the current end of the file
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	for {
+		if file == nil {
+			f, err := os.Open(path)
+			if err == nil {
+				file = f
+				if _, err := file.Seek(offset, io.SeekStart); err != nil {
+					return err
+				}
+			}
+		}
+
+		if file != nil {
+			n, err := io.Copy(out, file)
+			if err != nil {
+				return err
+			}
+			offset += n
+
+			// A different or shorter file at path means it was rotated
+			current, statErr := os.Stat(path)
+			opened, _ := file.Stat()
+			if statErr != nil || !os.SameFile(current, opened) || current.Size() < offset {
+				io.Copy(out, file)
+				file.Close()
+				file = nil
+				offset = 0
+			}
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the --output flag
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFlag is the root --output flag; empty means the command's default
+var outputFlag string
+
+// outputFormat is the format the running command writes, resolved by MarkStarted
+var outputFormat = outputTable
+
+// AddOutputFlag adds the --output flag shared by all commands to the root
+// command
+func AddOutputFlag(root *cobra.Command) {
+	root.PersistentFlags().StringVarP(&outputFlag, "output", "o", "",
+		"Output format: table, json or yaml (default table; claim and events default to json)")
+}
+
+// resolveOutput returns the format a command writes: --output if given, else
+// the older per-command --json and --format json flags, else the command's
+// default
+func resolveOutput(cmd *cobra.Command) (string, error) {
+	switch outputFlag {
+	case outputTable, outputJSON, outputYAML:
+		return outputFlag, nil
+	case "":
+	default:
+		return outputTable, errcode.New(errcode.InvalidArgument, "invalid --output '%s': must be table, json or yaml", outputFlag)
+	}
+
+	if f := cmd.Flags().Lookup("json"); f != nil && f.Value.String() == "true" {
+		return outputJSON, nil
+	}
+	if f := cmd.Flags().Lookup("format"); f != nil && f.Value.String() == "json" {
+		return outputJSON, nil
+	}
+	if format := cmd.Annotations["output"]; format != "" {
+		return format, nil
+	}
+	return outputTable, nil
+}
+
+// machineOutput reports whether stdout carries a JSON or YAML document, so
+// anything else must go to stderr
+func machineOutput() bool {
+	return outputFormat != outputTable
+}
+
+// printResult writes v to stdout as a JSON or YAML document, or calls table
+// to print it for people
+func printResult(v interface{}, table func() error) error {
+	if machineOutput() {
+		return printDocument(v)
+	}
+	return table()
+}
+
+// printDocument writes v to stdout in the JSON or YAML output format
+func printDocument(v interface{}) error {
+	if outputFormat == outputYAML {
+		data, err := marshalYAML(v)
+		if err != nil {
+			return fmt.Errorf("failed to create YAML output: %w", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to create JSON output: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// marshalYAML encodes v as YAML with the same keys, order and types as its
+// JSON encoding. JSON is valid YAML, so decoding it into a node tree keeps
+// all of that; only the flow style needs dropping.
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle clears the JSON styles of a node tree. Strings that would read
+// as another type unquoted are still quoted by the encoder.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// infof prints a progress message, on stderr when stdout carries a JSON or
+// YAML document
+func infof(format string, args ...interface{}) {
+	if machineOutput() {
+		fmt.Fprintf(os.Stderr, "[INFO] "+format+"\n", args...)
+		return
+	}
+	internal.PrintInfo(format, args...)
+}
+
+// warnf prints a warning, on stderr when stdout carries a JSON or YAML
+// document
+func warnf(format string, args ...interface{}) {
+	if machineOutput() {
+		fmt.Fprintf(os.Stderr, "[WARN] "+format+"\n", args...)
+		return
+	}
+	internal.PrintWarn(format, args...)
+}
+
+// useColor reports whether table output may use ANSI colors and terminal
+// hyperlinks: only on a terminal, and not when NO_COLOR is set
+// (https://no-color.org)
+func useColor() bool {
+	if outputFormat != outputTable || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	v := struct {
+		Name    string   `json:"name"`
+		Branch  *string  `json:"branch"`
+		SHA     string   `json:"sha"`
+		Ignored string   `json:"-"`
+		Tags    []string `json:"tags"`
+		Repo    struct {
+			Name string `json:"name"`
+		} `json:"repo"`
+	}{Name: "app", SHA: "0123", Ignored: "x", Tags: []string{}}
+
+	got, err := marshalYAML(v)
+	if err != nil {
+		t.Fatalf("marshalYAML() error = %v", err)
+	}
+	// JSON field names and order, and strings that look like numbers stay
+	// strings
+	want := "name: app\nbranch: null\nsha: \"0123\"\ntags: []\nrepo:\n  name: \"\"\n"
+	if string(got) != want {
+		t.Errorf("marshalYAML() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveOutput(t *testing.T) {
+	newCmd := func(annotation string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		if annotation != "" {
+			cmd.Annotations = map[string]string{"output": annotation}
+		}
+		cmd.Flags().Bool("json", false, "")
+		return cmd
+	}
+	defer func() { outputFlag = "" }()
+
+	tests := []struct {
+		name       string
+		flag       string
+		json       bool
+		annotation string
+		want       string
+		wantErr    bool
+	}{
+		{name: "default", want: outputTable},
+		{name: "command default", annotation: outputJSON, want: outputJSON},
+		{name: "legacy json flag", json: true, want: outputJSON},
+		{name: "flag wins over default", flag: outputTable, annotation: outputJSON, want: outputTable},
+		{name: "flag wins over legacy flag", flag: outputYAML, json: true, want: outputYAML},
+		{name: "invalid", flag: "xml", want: outputTable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newCmd(tt.annotation)
+			if tt.json {
+				cmd.Flags().Set("json", "true")
+			}
+			outputFlag = tt.flag
+
+			got, err := resolveOutput(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/albertywu/gitpool/internal"
@@ -24,7 +25,7 @@ to the latest commit on the default branch.`,
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			req := ipc.RefreshRequest{
 				RepoName: repoName,
 			}
@@ -35,12 +36,28 @@ to the latest commit on the default branch.`,
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to refresh repository: %s", resp.Error)
-				return fmt.Errorf("refresh failed")
+				return daemonError(resp, "failed to refresh repository")
 			}
 
-			internal.PrintInfo("Repository '%s' refreshed successfully", repoName)
-			return nil
+			data, _ := json.Marshal(resp.Data)
+			var refreshed struct {
+				Repository       string `json:"repository"`
+				WorktreesUpdated int    `json:"worktrees_updated"`
+				WorktreesCleaned int    `json:"worktrees_cleaned"`
+			}
+			if err := json.Unmarshal(data, &refreshed); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			result := map[string]interface{}{
+				"repo":              refreshed.Repository,
+				"worktrees_updated": refreshed.WorktreesUpdated,
+				"worktrees_cleaned": refreshed.WorktreesCleaned,
+			}
+			return printResult(result, func() error {
+				internal.PrintInfo("Repository '%s' refreshed successfully", repoName)
+				return nil
+			})
 		},
 	}
 }
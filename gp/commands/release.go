@@ -22,7 +22,7 @@ func NewReleaseCmd() *cobra.Command {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			req := ipc.ReleaseRequest{
 				WorktreeID: worktreeID,
 			}
@@ -33,12 +33,14 @@ func NewReleaseCmd() *cobra.Command {
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to release worktree: %s", resp.Error)
-				return fmt.Errorf("release failed")
+				return daemonError(resp, "failed to release worktree")
 			}
 
-			internal.PrintInfo("Worktree released successfully")
-			return nil
+			result := map[string]interface{}{"worktree_id": worktreeID, "released": true}
+			return printResult(result, func() error {
+				internal.PrintInfo("Worktree released successfully")
+				return nil
+			})
 		},
 	}
 }
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+func NewReloadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Apply config.yaml changes to the running daemon",
+		Long: `Make the running daemon re-read config.yaml and apply what changed.
+
+The daemon also reloads on its own when config.yaml is saved, and on SIGHUP.
+reconciliation_interval, claim_retention, log_level and autostart apply
+immediately. Changes to other settings, invalid values and unknown settings
+are reported and leave the running configuration unchanged.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := newClient(cfg)
+			resp, err := client.Reload()
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to reload config")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var reload ipc.ReloadResponse
+			if err := json.Unmarshal(data, &reload); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if reload.Applied == nil {
+				reload.Applied = []string{}
+			}
+			if reload.Rejected == nil {
+				reload.Rejected = []ipc.RejectedSetting{}
+			}
+			err = printResult(reload, func() error {
+				if len(reload.Applied) == 0 && len(reload.Rejected) == 0 {
+					internal.PrintInfo("No config changes")
+					return nil
+				}
+				for _, change := range reload.Applied {
+					internal.PrintInfo("Applied %s", change)
+				}
+				for _, r := range reload.Rejected {
+					internal.PrintWarn("Not applied: %s: %s", r.Field, r.Reason)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(reload.Rejected) > 0 {
+				return fmt.Errorf("some config changes were not applied")
+			}
+			return nil
+		},
+	}
+}
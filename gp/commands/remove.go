@@ -5,7 +5,6 @@ import (
 
 	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
-	"github.com/albertywu/gitpool/internal/ipc"
 	"github.com/spf13/cobra"
 )
 
@@ -23,19 +22,21 @@ func NewRemoveCmd() *cobra.Command {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			resp, err := client.RepoRemove(name)
 			if err != nil {
 				return fmt.Errorf("failed to communicate with daemon: %w", err)
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to remove repository: %s", resp.Error)
-				return fmt.Errorf("remove repository failed")
+				return daemonError(resp, "failed to remove repository")
 			}
 
-			internal.PrintInfo("Repository '%s' removed successfully", name)
-			return nil
+			result := map[string]interface{}{"repo": name, "removed": true}
+			return printResult(result, func() error {
+				internal.PrintInfo("Repository '%s' removed successfully", name)
+				return nil
+			})
 		},
 	}
 }
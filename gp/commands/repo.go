@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repoUpdateMaxWorktrees int
+	repoUpdateBaseBranch   string
+	repoUpdateFetchEvery   time.Duration
+)
+
+func NewRepoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo",
+		Short: "Manage tracked repositories",
+	}
+
+	cmd.AddCommand(newRepoUpdateCmd())
+
+	return cmd
+}
+
+func newRepoUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update <repo-name>",
+		Short: "Change the pool size, base branch or fetch interval of a tracked repository",
+		Long: `Change the settings of a tracked repository without untracking it.
+
+Lowering --max removes idle worktrees until the pool fits; claimed worktrees
+are left alone and removed once they are released. Raising it creates new
+worktrees up to the new size.
+
+Changing --base-branch moves every idle worktree to the tip of the new base
+branch. Claimed worktrees keep their branch and are reset to the new base
+branch when released.
+
+--fetch-interval makes the reconciler fetch the repository and move its idle
+worktrees to the tip of the base branch that often, like 'gp refresh'; 0
+turns scheduled fetches off.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			req := ipc.RepoUpdateRequest{Name: name}
+			if cmd.Flags().Changed("max") {
+				req.MaxWorktrees = &repoUpdateMaxWorktrees
+			}
+			if cmd.Flags().Changed("base-branch") {
+				req.BaseBranch = &repoUpdateBaseBranch
+			}
+			if cmd.Flags().Changed("fetch-interval") {
+				if repoUpdateFetchEvery < 0 || repoUpdateFetchEvery%time.Minute != 0 {
+					return errcode.New(errcode.InvalidArgument, "--fetch-interval must be a whole number of minutes")
+				}
+				minutes := int(repoUpdateFetchEvery / time.Minute)
+				req.FetchInterval = &minutes
+			}
+			if req.MaxWorktrees == nil && req.BaseBranch == nil && req.FetchInterval == nil {
+				return errcode.New(errcode.InvalidArgument, "nothing to update: specify --max, --base-branch or --fetch-interval")
+			}
+
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := newClient(cfg)
+			resp, err := client.RepoUpdate(req)
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+
+			if !resp.Success {
+				return daemonError(resp, "failed to update repository")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var updated models.Repository
+			if err := json.Unmarshal(data, &updated); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			return printResult(repoOutput(&updated), func() error {
+				internal.PrintInfo("Repository '%s' updated (max %d, base branch %s)", name, updated.MaxWorktrees, updated.BaseBranch)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().IntVar(&repoUpdateMaxWorktrees, "max", 0, "Maximum number of worktrees")
+	cmd.Flags().StringVar(&repoUpdateBaseBranch, "base-branch", "", "Base branch for worktrees")
+	cmd.Flags().DurationVar(&repoUpdateFetchEvery, "fetch-interval", 0, "Fetch and update idle worktrees this often, e.g. 15m (0 = only on refresh)")
+
+	return cmd
+}
+
+// repoOutput is the JSON form of a repository, as written by track and
+// repo update
+func repoOutput(repo *models.Repository) map[string]interface{} {
+	return map[string]interface{}{
+		"repo":                   repo.Name,
+		"path":                   repo.Path,
+		"max_worktrees":          repo.MaxWorktrees,
+		"base_branch":            repo.BaseBranch,
+		"fetch_interval_minutes": repo.FetchInterval,
+		"last_fetch":             repo.LastFetchTime,
+		"created_at":             repo.CreatedAt,
+	}
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+var restartTimeout time.Duration
+
+func NewRestartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the daemon without dropping requests",
+		Long: `Restart the running daemon using this gp binary, e.g. after an upgrade.
+
+The running daemon hands its socket to a new daemon process and finishes the
+requests it is already handling, including pending claims, before exiting.
+Requests made in the meantime wait for the new daemon instead of failing. The
+new daemon keeps the old one's paths and log file and resumes the reconciler
+schedule from the database.
+
+Event streams ('gp events --follow') are closed and must reconnect.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate gp executable: %w", err)
+			}
+			if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+				exe = resolved
+			}
+
+			client := ipc.NewClient(cfg.SocketPath)
+			resp, err := client.Restart(exe)
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to restart daemon")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var restart ipc.RestartResponse
+			if err := json.Unmarshal(data, &restart); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			infof("Handed over to new daemon (pid %d), waiting for it to take over...", restart.NewPID)
+
+			// The first request answered by the new daemon shows it has taken
+			// over; until then requests queue on the socket
+			client.Timeout = restartTimeout
+			deadline := time.Now().Add(restartTimeout)
+			for time.Now().Before(deadline) {
+				resp, err := client.DaemonStatus()
+				if err == nil && resp.Success && statusPID(resp) == restart.NewPID {
+					return printResult(restart, func() error {
+						internal.PrintInfo("Daemon restarted (pid %d -> %d)", restart.OldPID, restart.NewPID)
+						return nil
+					})
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			return errcode.New(errcode.DaemonUnavailable, "new daemon (pid %d) did not take over within %s, see its log", restart.NewPID, restartTimeout)
+		},
+	}
+
+	cmd.Flags().DurationVar(&restartTimeout, "timeout", 2*time.Minute, "How long to wait for the new daemon to take over")
+
+	return cmd
+}
+
+func statusPID(resp *ipc.Response) int {
+	data, _ := json.Marshal(resp.Data)
+	var status daemonStatusView
+	if err := json.Unmarshal(data, &status); err != nil {
+		return 0
+	}
+	return status.PID
+}
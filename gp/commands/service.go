@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serviceUnitName = "gitpool.service"
+	socketUnitName  = "gitpool.socket"
+)
+
+var (
+	serviceSocket  bool
+	serviceNoStart bool
+)
+
+func NewServiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the daemon as a systemd user service",
+		Long: `Install, remove or inspect a systemd user service that runs the gitpool daemon.
+
+The units are written to ~/.config/systemd/user. With --socket a socket unit
+is installed as well: systemd owns the daemon socket and starts the daemon on
+the first connection. Daemon logs go to the journal:
+
+  journalctl --user -u gitpool`,
+	}
+
+	cmd.AddCommand(newServiceInstallCmd())
+	cmd.AddCommand(newServiceUninstallCmd())
+	cmd.AddCommand(newServiceStatusCmd())
+
+	return cmd
+}
+
+func newServiceInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install and start the systemd user units",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate gp executable: %w", err)
+			}
+			if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+				exe = resolved
+			}
+
+			dir, err := systemdUserDir()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+
+			servicePath := filepath.Join(dir, serviceUnitName)
+			if err := os.WriteFile(servicePath, []byte(serviceUnit(cfg, exe, serviceSocket)), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", servicePath, err)
+			}
+			infof("Wrote %s", servicePath)
+			files := []string{servicePath}
+
+			socketPath := filepath.Join(dir, socketUnitName)
+			if serviceSocket {
+				if err := os.WriteFile(socketPath, []byte(socketUnit(cfg)), 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", socketPath, err)
+				}
+				infof("Wrote %s", socketPath)
+				files = append(files, socketPath)
+			} else if err := os.Remove(socketPath); err == nil {
+				// A socket unit left over from an earlier install would
+				// compete with the daemon for the socket path
+				systemctl("disable", "--now", socketUnitName)
+				infof("Removed %s", socketPath)
+			}
+
+			unit := serviceUnitName
+			if serviceSocket {
+				unit = socketUnitName
+			}
+			installed := func(enabled, started bool) error {
+				if !machineOutput() {
+					return nil
+				}
+				return printDocument(map[string]interface{}{
+					"unit":    unit,
+					"files":   files,
+					"enabled": enabled,
+					"started": started,
+				})
+			}
+
+			if _, err := systemctl("daemon-reload"); err != nil {
+				warnf("Could not reload systemd: %v", err)
+				warnf("Run: systemctl --user daemon-reload && systemctl --user enable --now %s", unit)
+				return installed(false, false)
+			}
+
+			if serviceNoStart {
+				infof("Start it with: systemctl --user enable --now %s", unit)
+				return installed(false, false)
+			}
+
+			if daemon.CheckDaemonRunning(cfg.SocketPath) {
+				warnf("A daemon is already running; stop it with 'gp stop' before starting the service")
+				if _, err := systemctl("enable", unit); err != nil {
+					return fmt.Errorf("failed to enable %s: %w", unit, err)
+				}
+				infof("Enabled %s", unit)
+				return installed(true, false)
+			}
+
+			if _, err := systemctl("enable", "--now", unit); err != nil {
+				return fmt.Errorf("failed to enable %s: %w", unit, err)
+			}
+			infof("Enabled and started %s", unit)
+			return installed(true, true)
+		},
+	}
+
+	cmd.Flags().BoolVar(&serviceSocket, "socket", false, "Also install a socket unit so systemd starts the daemon on first connection")
+	cmd.Flags().BoolVar(&serviceNoStart, "no-start", false, "Only write the unit files")
+
+	return cmd
+}
+
+func newServiceUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop and remove the systemd user units",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := systemdUserDir()
+			if err != nil {
+				return err
+			}
+
+			// Stop the socket first so it can't re-activate the service
+			removed := []string{}
+			for _, unit := range []string{socketUnitName, serviceUnitName} {
+				path := filepath.Join(dir, unit)
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					continue
+				}
+
+				if out, err := systemctl("disable", "--now", unit); err != nil {
+					warnf("Could not disable %s: %v %s", unit, err, strings.TrimSpace(out))
+				}
+				if err := os.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", path, err)
+				}
+				infof("Removed %s", path)
+				removed = append(removed, path)
+			}
+
+			if len(removed) == 0 {
+				infof("No gitpool units installed in %s", dir)
+			} else {
+				systemctl("daemon-reload")
+			}
+
+			if machineOutput() {
+				return printDocument(map[string]interface{}{"removed": removed})
+			}
+			return nil
+		},
+	}
+}
+
+func newServiceStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the systemd user units",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			dir, err := systemdUserDir()
+			if err != nil {
+				return err
+			}
+
+			type unitState struct {
+				Unit      string `json:"unit"`
+				Installed bool   `json:"installed"`
+				Enabled   string `json:"enabled"`
+				Active    string `json:"active"`
+			}
+			var units []unitState
+			for _, unit := range []string{serviceUnitName, socketUnitName} {
+				state := unitState{Unit: unit, Enabled: "-", Active: "-"}
+				if _, err := os.Stat(filepath.Join(dir, unit)); err == nil {
+					state.Installed = true
+					state.Enabled = systemctlState("is-enabled", unit)
+					state.Active = systemctlState("is-active", unit)
+				}
+				units = append(units, state)
+			}
+			responding := daemon.CheckDaemonRunning(cfg.SocketPath)
+
+			result := map[string]interface{}{
+				"units":             units,
+				"socket_path":       cfg.SocketPath,
+				"daemon_responding": responding,
+			}
+			return printResult(result, func() error {
+				w := internal.NewTabWriter()
+				fmt.Fprintln(w, "UNIT\tINSTALLED\tENABLED\tACTIVE")
+				for _, u := range units {
+					installed := "no"
+					if u.Installed {
+						installed = "yes"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Unit, installed, u.Enabled, u.Active)
+				}
+				w.Flush()
+
+				if responding {
+					fmt.Printf("\nDaemon is responding on %s\n", cfg.SocketPath)
+				} else {
+					fmt.Printf("\nDaemon is not responding on %s\n", cfg.SocketPath)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// serviceUnit renders the service unit. Paths are passed explicitly so the
+// service uses the same directories as the installing shell.
+func serviceUnit(cfg *config.Config, exe string, socketActivated bool) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=gitpool worktree pool daemon\n")
+	b.WriteString("Documentation=https://github.com/albertywu/gitpool\n")
+	if socketActivated {
+		b.WriteString("Requires=" + socketUnitName + "\n")
+		b.WriteString("After=" + socketUnitName + "\n")
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s start --config-dir %s --worktree-dir %s --socket-path %s\n",
+		systemdQuote(exe), systemdQuote(cfg.ConfigDir), systemdQuote(cfg.WorktreeDir), systemdQuote(cfg.SocketPath))
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=2\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// socketUnit renders the socket unit that owns the daemon's socket path
+func socketUnit(cfg *config.Config) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=gitpool daemon socket\n")
+	b.WriteString("\n[Socket]\n")
+	fmt.Fprintf(&b, "ListenStream=%s\n", cfg.SocketPath)
+	b.WriteString("SocketMode=0600\n")
+	b.WriteString("RemoveOnStop=yes\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=sockets.target\n")
+	return b.String()
+}
+
+// systemdQuote quotes a command line argument for ExecStart if needed
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;$%") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+func systemdUserDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "systemd", "user"), nil
+}
+
+func systemctl(args ...string) (string, error) {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	return string(out), err
+}
+
+// systemctlState returns the one-word answer of is-enabled/is-active, which
+// exit non-zero for negative answers
+func systemctlState(query, unit string) string {
+	out, _ := systemctl(query, unit)
+	// Errors such as an unreachable user bus come back as sentences
+	if state := strings.TrimSpace(out); state != "" && !strings.ContainsAny(state, " \n") {
+		return state
+	}
+	return "unknown"
+}
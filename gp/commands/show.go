@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/albertywu/gitpool/internal"
+	"os/user"
+	"strconv"
+
 	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/ipc"
 	"github.com/albertywu/gitpool/internal/models"
@@ -18,7 +20,7 @@ func NewShowCmd() *cobra.Command {
 		Use:   "show <worktree-id>",
 		Short: "Show details about a specific worktree",
 		Long: `Display detailed information about a worktree including its path, status, branch, and claim time.
-Use --format flag to get specific fields for scripting.`,
+Use --format path to print just the path, or --output json for scripting.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			worktreeID := args[0]
@@ -28,7 +30,7 @@ Use --format flag to get specific fields for scripting.`,
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			req := ipc.ShowRequest{
 				WorktreeID: worktreeID,
 			}
@@ -39,8 +41,7 @@ Use --format flag to get specific fields for scripting.`,
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to get worktree details: %s", resp.Error)
-				return fmt.Errorf("show failed")
+				return daemonError(resp, "failed to get worktree details")
 			}
 
 			// Parse response
@@ -50,23 +51,12 @@ Use --format flag to get specific fields for scripting.`,
 				return fmt.Errorf("failed to parse response: %w", err)
 			}
 
-			// Format output based on format flag
-			switch showFormat {
-			case "path":
+			if showFormat == "path" {
 				fmt.Println(detail.Worktree.Path)
-			case "json":
-				output := map[string]interface{}{
-					"worktree_id": detail.Worktree.Name,
-					"path":        detail.Worktree.Path,
-					"repo":        detail.Repository.Name,
-					"branch":      detail.Worktree.Branch,
-					"status":      detail.Worktree.Status,
-					"claimed_at":  detail.Worktree.LeasedAt,
-				}
-				jsonBytes, _ := json.MarshalIndent(output, "", "  ")
-				fmt.Println(string(jsonBytes))
-			default:
-				// Default human-readable format
+				return nil
+			}
+
+			return printResult(worktreeOutput(&detail), func() error {
 				fmt.Printf("Worktree ID: %s\n", detail.Worktree.Name)
 				fmt.Printf("Path:        %s\n", detail.Worktree.Path)
 				fmt.Printf("Repository:  %s\n", detail.Repository.Name)
@@ -77,13 +67,40 @@ Use --format flag to get specific fields for scripting.`,
 				if detail.Worktree.LeasedAt != nil {
 					fmt.Printf("Claimed at:  %s\n", detail.Worktree.LeasedAt.Format("2006-01-02 15:04:05"))
 				}
-			}
-
-			return nil
+				if detail.Worktree.Status.HoldsBranch() && detail.Worktree.OwnerUID != nil {
+					fmt.Printf("Owner:       %s\n", ownerName(*detail.Worktree.OwnerUID))
+				}
+				return nil
+			})
 		},
 	}
 
-	cmd.Flags().StringVar(&showFormat, "format", "", "Output format: path, json (default: human-readable)")
+	cmd.Flags().StringVar(&showFormat, "format", "", "Print only the path with 'path'; 'json' is the same as --output json")
 
 	return cmd
 }
+
+// worktreeOutput is the JSON form of a worktree, as written by show and list
+func worktreeOutput(detail *models.WorktreeDetail) map[string]interface{} {
+	var ownerUID *int
+	if detail.Worktree.Status.HoldsBranch() {
+		ownerUID = detail.Worktree.OwnerUID
+	}
+	return map[string]interface{}{
+		"worktree_id": detail.Worktree.Name,
+		"path":        detail.Worktree.Path,
+		"repo":        detail.Repository.Name,
+		"branch":      detail.Worktree.Branch,
+		"status":      detail.Worktree.Status,
+		"claimed_at":  detail.Worktree.LeasedAt,
+		"owner_uid":   ownerUID,
+	}
+}
+
+// ownerName returns the login name of a user, or the uid if it has none
+func ownerName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
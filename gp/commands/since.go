@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+)
+
+// parseSince converts a --since value into an absolute time. It accepts Go
+// durations ("90m", "36h"), whole days ("7d"), dates ("2006-01-02") and
+// RFC 3339 timestamps.
+func parseSince(value string) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, errcode.New(errcode.InvalidArgument, "invalid --since value '%s' (use e.g. 7d, 12h or 2006-01-02)", value)
+}
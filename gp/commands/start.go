@@ -2,10 +2,21 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/daemon"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/logfile"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -13,23 +24,56 @@ var (
 	startConfigDir   string
 	startWorktreeDir string
 	startSocketPath  string
+	startBackground  bool
+	startLogFile     string
 )
 
+// startReadyTimeout is how long --background waits for the daemon to answer
+const startReadyTimeout = 15 * time.Second
+
 func NewStartCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the gitpool daemon",
-		Long:  `Start the gitpool daemon to manage the worktree pool in the background.`,
+		Long: `Start the gitpool daemon to manage the worktree pool in the background.
+
+By default the daemon runs in the foreground and logs to stderr. With
+--background it detaches from the terminal, logs to a rotating file under the
+config directory (~/.gitpool/logs/daemon.log) and the command returns once the
+daemon is ready to serve requests. Use 'gp logs' to read the log.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := config.LoadWithCustomPaths(startConfigDir, startWorktreeDir, startSocketPath)
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+			// Report a bad config here rather than from a background daemon
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			// Check if daemon is already running. When the socket is passed in
+			// by systemd or a restarting daemon it is ours, and probing it
+			// would only queue behind us.
+			if !ipc.SocketActivated() && !ipc.HandoffPending() && daemon.CheckDaemonRunning(cfg.SocketPath) {
+				return errcode.New(errcode.Conflict, "another instance is already running (socket lock exists)")
+			}
 
-			// Check if daemon is already running
-			if daemon.CheckDaemonRunning(cfg.SocketPath) {
-				internal.PrintError("Another instance is already running (socket lock exists)")
-				return fmt.Errorf("daemon already running")
+			if startBackground {
+				return startInBackground(cfg)
+			}
+
+			var logOutput io.Writer = os.Stderr
+			if startLogFile != "" {
+				w, err := logfile.Open(startLogFile, int64(cfg.LogMaxSizeMB)*1024*1024, cfg.LogMaxBackups)
+				if err != nil {
+					return err
+				}
+				defer w.Close()
+				logOutput = w
+			}
+
+			if err := logging.Setup(logOutput, cfg.LogFormat, cfg.LogLevel); err != nil {
+				return fmt.Errorf("invalid logging config: %w", err)
 			}
 
 			// Create and start daemon
@@ -38,7 +82,7 @@ func NewStartCmd() *cobra.Command {
 				return fmt.Errorf("failed to create daemon: %w", err)
 			}
 
-			internal.PrintInfo("Starting gitpool daemon...")
+			infof("Starting gitpool daemon...")
 			return d.Start()
 		},
 	}
@@ -46,6 +90,122 @@ func NewStartCmd() *cobra.Command {
 	cmd.Flags().StringVar(&startConfigDir, "config-dir", "", "Custom config directory")
 	cmd.Flags().StringVar(&startWorktreeDir, "worktree-dir", "", "Custom worktree directory")
 	cmd.Flags().StringVar(&startSocketPath, "socket-path", "", "Custom socket path")
+	cmd.Flags().BoolVarP(&startBackground, "background", "d", false, "Detach and run the daemon in the background")
+	cmd.Flags().StringVar(&startLogFile, "log-file", "", "Write daemon logs to this file instead of stderr")
 
 	return cmd
 }
+
+// startInBackground re-executes gp as a detached daemon process and waits
+// until it answers on its socket
+func startInBackground(cfg *config.Config) error {
+	logPath := startLogFile
+	if logPath == "" {
+		logPath = cfg.LogFile()
+	}
+
+	pid, err := spawnDaemon(cfg, logPath)
+	if err != nil {
+		return fmt.Errorf("daemon failed to start: %w", err)
+	}
+
+	result := map[string]interface{}{"pid": pid, "log_file": logPath}
+	return printResult(result, func() error {
+		internal.PrintInfo("Daemon started in background (pid %d)", pid)
+		internal.PrintInfo("Logs: %s", logPath)
+		return nil
+	})
+}
+
+// spawnDaemon starts a detached daemon logging to logPath and returns its
+// PID once it is ready to serve requests
+func spawnDaemon(cfg *config.Config, logPath string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate gp executable: %w", err)
+	}
+
+	// Pass the resolved paths explicitly so the child can't pick up
+	// different ones from its environment
+	args := []string{"start",
+		"--config-dir", cfg.ConfigDir,
+		"--worktree-dir", cfg.WorktreeDir,
+		"--socket-path", cfg.SocketPath,
+		"--log-file", logPath,
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	// Errors from before the daemon's logger is set up, e.g. a held lock or
+	// a socket in use, go to the log file too, where they can be reported
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	stderr, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", logPath, err)
+	}
+	defer stderr.Close()
+	offset, _ := stderr.Seek(0, io.SeekEnd)
+
+	child := exec.Command(exe, args...)
+	child.Stdin = devNull
+	child.Stdout = devNull
+	child.Stderr = stderr
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := child.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start daemon process: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- child.Wait()
+	}()
+
+	deadline := time.After(startReadyTimeout)
+	for {
+		if daemon.CheckDaemonRunning(cfg.SocketPath) {
+			return child.Process.Pid, nil
+		}
+
+		select {
+		case err := <-exited:
+			if reason := startupError(logPath, offset); reason != "" {
+				return 0, fmt.Errorf("daemon exited during startup: %s", reason)
+			}
+			return 0, fmt.Errorf("daemon exited during startup (%v), see %s", err, logPath)
+		case <-deadline:
+			return 0, fmt.Errorf("daemon did not become ready within %s, see %s", startReadyTimeout, logPath)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// startupError returns the error a daemon that exited during startup wrote
+// to its log after offset: the last line it wrote, if that is an error
+func startupError(logPath string, offset int64) string {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return ""
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "[ERROR] ") {
+		return ""
+	}
+	return strings.TrimPrefix(last, "[ERROR] ")
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsRepo  string
+	statsSince string
+	statsJSON  bool
+)
+
+func NewStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show pool utilization and latency statistics",
+		Long: `Analyze the claim history to help size each repository's pool.
+
+For every repository the report shows:
+  - claims rejected because the pool was at capacity
+  - peak number of concurrent claims
+  - time the pool spent with every worktree claimed
+  - median and 95th percentile claim latency
+  - median lease duration
+  - share of releases that left the worktree corrupt
+  - a recommended --max: the size that would have served 95% of claims,
+    rejected ones included, without waiting, plus one spare
+
+Statistics only cover the claim history that is still retained
+(claim_retention, default 30 days).
+
+Examples:
+  gp stats --since 7d
+  gp stats --repo my-app --since 24h --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseSince(statsSince)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := newClient(cfg)
+			resp, err := client.Stats(ipc.StatsRequest{RepoName: statsRepo, Since: since})
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+
+			if !resp.Success {
+				return daemonError(resp, "failed to get statistics")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var reports []*stats.Report
+			if err := json.Unmarshal(data, &reports); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			output := make([]map[string]interface{}, 0, len(reports))
+			for _, r := range reports {
+				output = append(output, map[string]interface{}{
+					"repo":                      r.RepoName,
+					"since":                     r.Since,
+					"until":                     r.Until,
+					"max_worktrees":             r.MaxWorktrees,
+					"claims":                    r.Claims,
+					"rejected_claims":           r.RejectedClaims,
+					"peak_concurrent_claims":    r.PeakConcurrent,
+					"time_at_capacity_seconds":  r.TimeAtCapacity.Seconds(),
+					"claim_latency_p50_seconds": r.ClaimLatencyP50.Seconds(),
+					"claim_latency_p95_seconds": r.ClaimLatencyP95.Seconds(),
+					"lease_p50_seconds":         r.LeaseP50.Seconds(),
+					"corrupt_rate":              r.CorruptRate,
+					"recommended_max_worktrees": r.RecommendedSize,
+				})
+			}
+
+			return printResult(output, func() error {
+				if len(reports) == 0 {
+					fmt.Println("No repositories tracked")
+					return nil
+				}
+
+				w := internal.NewTabWriter()
+				fmt.Fprintln(w, "REPO\tMAX\tCLAIMS\tREJECTED\tPEAK\tAT_CAPACITY\tLATENCY_P50\tLATENCY_P95\tLEASE_P50\tCORRUPT\tRECOMMENDED")
+				for _, r := range reports {
+					fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%.1f%%\t%d\n",
+						r.RepoName,
+						r.MaxWorktrees,
+						r.Claims,
+						r.RejectedClaims,
+						r.PeakConcurrent,
+						internal.FormatDuration(r.TimeAtCapacity),
+						r.ClaimLatencyP50.Round(time.Millisecond),
+						r.ClaimLatencyP95.Round(time.Millisecond),
+						internal.FormatDuration(r.LeaseP50),
+						r.CorruptRate*100,
+						r.RecommendedSize)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&statsRepo, "repo", "", "Only report on this repository")
+	cmd.Flags().StringVar(&statsSince, "since", "7d", "Start of the reporting window (e.g. 7d, 12h, 2006-01-02)")
+	cmd.Flags().BoolVar(&statsJSON, "json", false, "Output as JSON (same as --output json)")
+
+	return cmd
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+// daemonStatusView mirrors the daemon_status response
+type daemonStatusView struct {
+	Running        bool       `json:"running"`
+	Version        string     `json:"version"`
+	PID            int        `json:"pid"`
+	StartedAt      time.Time  `json:"started_at"`
+	Uptime         string     `json:"uptime"`
+	SocketPath     string     `json:"socket_path"`
+	HTTPListen     string     `json:"http_listen,omitempty"`
+	WorktreeDir    string     `json:"worktree_dir"`
+	LastReconciler *time.Time `json:"last_reconciler"`
+	Repositories   int        `json:"repositories"`
+	// Quotas is the usage of each configured quota
+	Quotas []models.QuotaUsage `json:"quotas"`
+}
+
+func NewStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [repo-name]",
+		Short: "Show daemon and pool status",
+		Long: `Show the state of the daemon and of each repository's worktree pool.
+
+For every repository the pool line shows how many worktrees are idle, in use
+and corrupt against the configured maximum, when the repository was last
+fetched, the base branch commit idle worktrees should be at, and how many
+idle worktrees lag behind it. Run 'gp refresh <repo>' to bring them up to date.
+
+When claim quotas are configured, the claims each user and owner label holds
+against them are listed too.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var repoName string
+			if len(args) == 1 {
+				repoName = args[0]
+			}
+
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			client := ipc.NewClient(cfg.SocketPath)
+
+			resp, err := client.DaemonStatus()
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to get daemon status")
+			}
+
+			data, _ := json.Marshal(resp.Data)
+			var daemonStatus daemonStatusView
+			if err := json.Unmarshal(data, &daemonStatus); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			resp, err = client.PoolStatus(ipc.PoolStatusRequest{RepoName: repoName})
+			if err != nil {
+				return fmt.Errorf("failed to communicate with daemon: %w", err)
+			}
+			if !resp.Success {
+				return daemonError(resp, "failed to get pool status")
+			}
+
+			data, _ = json.Marshal(resp.Data)
+			var pools []*models.PoolStatus
+			if err := json.Unmarshal(data, &pools); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			repos := make([]map[string]interface{}, 0, len(pools))
+			for _, p := range pools {
+				repos = append(repos, map[string]interface{}{
+					"repo":               p.RepoName,
+					"total":              p.Total,
+					"idle":               p.Idle,
+					"in_use":             p.InUse,
+					"corrupt":            p.Corrupt,
+					"max":                p.Max,
+					"last_fetch":         p.LastFetch,
+					"base_branch":        p.BaseBranch,
+					"base_sha":           p.BaseSHA,
+					"stale_idle":         p.StaleIdle,
+					"max_commits_behind": p.MaxBehind,
+				})
+			}
+			output := map[string]interface{}{
+				"daemon":       daemonStatus,
+				"repositories": repos,
+			}
+
+			return printResult(output, func() error {
+				return printStatus(&daemonStatus, pools)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&statusJSON, "json", false, "Output as JSON (same as --output json)")
+
+	return cmd
+}
+
+// printStatus prints the daemon and pool status for people
+func printStatus(daemonStatus *daemonStatusView, pools []*models.PoolStatus) error {
+	fmt.Printf("Daemon:          running (pid %d, version %s)\n", daemonStatus.PID, daemonStatus.Version)
+	fmt.Printf("Uptime:          %s\n", internal.FormatDuration(time.Since(daemonStatus.StartedAt)))
+	fmt.Printf("Socket:          %s\n", daemonStatus.SocketPath)
+	if daemonStatus.HTTPListen != "" {
+		fmt.Printf("HTTP API:        http://%s\n", daemonStatus.HTTPListen)
+	}
+	fmt.Printf("Worktree dir:    %s\n", daemonStatus.WorktreeDir)
+	fmt.Printf("Last reconciler: %s\n", internal.FormatTime(daemonStatus.LastReconciler))
+	fmt.Println()
+
+	if len(pools) == 0 {
+		fmt.Println("No repositories tracked")
+		return nil
+	}
+
+	w := internal.NewTabWriter()
+	fmt.Fprintln(w, "REPO\tIDLE\tIN_USE\tCORRUPT\tTOTAL/MAX\tLAST_FETCH\tBASE\tIDLE_BEHIND")
+	for _, p := range pools {
+		base := p.BaseBranch
+		if len(p.BaseSHA) >= 7 {
+			base = fmt.Sprintf("%s@%s", p.BaseBranch, p.BaseSHA[:7])
+		}
+
+		behind := "up to date"
+		if p.BaseSHA == "" {
+			behind = "unknown"
+		} else if p.StaleIdle > 0 {
+			behind = fmt.Sprintf("%d stale, up to %d commits", p.StaleIdle, p.MaxBehind)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d/%d\t%s\t%s\t%s\n",
+			p.RepoName,
+			p.Idle,
+			p.InUse,
+			p.Corrupt,
+			p.Total, p.Max,
+			internal.FormatTime(p.LastFetch),
+			base,
+			behind)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(daemonStatus.Quotas) > 0 {
+		fmt.Println()
+		w = internal.NewTabWriter()
+		fmt.Fprintln(w, "QUOTA\tOWNER\tREPO\tUSED/LIMIT")
+		for _, q := range daemonStatus.Quotas {
+			owner := q.Label
+			if q.UID != nil {
+				owner = ownerName(*q.UID)
+			}
+			repo := q.Repo
+			if repo == "" {
+				repo = "(all)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\n", q.Scope, owner, repo, q.Used, q.Limit)
+		}
+		return w.Flush()
+	}
+	return nil
+}
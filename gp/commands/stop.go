@@ -1,57 +1,174 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"syscall"
+	"time"
 
 	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/daemon"
+	"github.com/albertywu/gitpool/internal/errcode"
 	"github.com/albertywu/gitpool/internal/ipc"
 	"github.com/spf13/cobra"
 )
 
+var (
+	stopTimeout time.Duration
+	stopForce   bool
+)
+
+// stopKillGrace is how long to wait after SIGTERM before sending SIGKILL
+const stopKillGrace = 5 * time.Second
+
 func NewStopCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the gitpool daemon",
-		Long:  `Stop the running gitpool daemon gracefully.`,
+		Long: `Stop the running gitpool daemon gracefully.
+
+The daemon stops accepting new requests, waits for in-flight claims and
+releases and any running reconciliation to finish, and then exits. Worktrees
+that are still claimed stay claimed and are reported; they are kept as-is
+until the daemon is started again.
+
+If the daemon does not stop within --timeout, --force sends SIGTERM and then
+SIGKILL to the process recorded in the daemon's PID file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := config.LoadWithCustomPaths("", "", "")
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
+			pid, _ := daemon.ReadPIDFile(cfg.PIDFile())
+
 			// Check if socket exists
 			if _, err := os.Stat(cfg.SocketPath); os.IsNotExist(err) {
-				internal.PrintError("Daemon is not running")
-				return fmt.Errorf("daemon not running")
+				if stopForce && daemon.ProcessAlive(pid) {
+					warnf("Socket is missing but daemon process %d is running", pid)
+					return killDaemon(cfg, pid)
+				}
+				return errcode.New(errcode.DaemonUnavailable, "daemon is not running")
 			}
 
-			// Try to connect and check status first
+			infof("Stopping gitpool daemon...")
+
 			client := ipc.NewClient(cfg.SocketPath)
-			resp, err := client.DaemonStatus()
+			client.Timeout = stopTimeout
+			deadline := time.Now().Add(stopTimeout)
+
+			resp, err := client.Shutdown()
 			if err != nil {
-				// Socket exists but can't connect - might be stale
-				internal.PrintWarn("Socket exists but daemon not responding, cleaning up...")
-				os.Remove(cfg.SocketPath)
-				return nil
+				if !daemon.ProcessAlive(pid) {
+					// Socket exists but nobody is serving it - stale
+					warnf("Socket exists but daemon not responding, cleaning up...")
+					os.Remove(cfg.SocketPath)
+					os.Remove(cfg.PIDFile())
+					return printStopped(pid, false, nil)
+				}
+				if stopForce {
+					warnf("Daemon did not shut down gracefully: %v", err)
+					return killDaemon(cfg, pid)
+				}
+				return fmt.Errorf("daemon did not shut down gracefully (use --force to kill it): %w", err)
 			}
 
 			if !resp.Success {
-				internal.PrintError("Daemon is not running")
-				return fmt.Errorf("daemon not running")
+				return daemonError(resp, "failed to stop daemon")
 			}
 
-			// Send interrupt signal to daemon process
-			// In a real implementation, we'd need to track the PID
-			internal.PrintInfo("Stopping gitpool daemon...")
+			data, _ := json.Marshal(resp.Data)
+			var shutdown ipc.ShutdownResponse
+			if err := json.Unmarshal(data, &shutdown); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
 
-			// For now, we'll just note that users should use Ctrl+C
-			internal.PrintInfo("Please use Ctrl+C to stop the daemon process")
+			if !waitForExit(cfg, shutdown.PID, time.Until(deadline)) {
+				if stopForce {
+					warnf("Daemon did not exit within %s", stopTimeout)
+					return killDaemon(cfg, shutdown.PID)
+				}
+				return fmt.Errorf("daemon did not exit within %s (use --force to kill it)", stopTimeout)
+			}
 
-			return nil
+			return printStopped(shutdown.PID, false, shutdown.InUseWorktrees)
 		},
 	}
 
+	cmd.Flags().DurationVar(&stopTimeout, "timeout", 30*time.Second, "How long to wait for a graceful shutdown")
+	cmd.Flags().BoolVar(&stopForce, "force", false, "Send SIGTERM, then SIGKILL, if the daemon does not stop in time")
+
 	return cmd
 }
+
+// waitForExit polls until the daemon process is gone or has removed its PID
+// file, which is the last thing it does before exiting. Without a PID it
+// waits for the socket to disappear.
+func waitForExit(cfg *config.Config, pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if pid > 0 {
+			if current, _ := daemon.ReadPIDFile(cfg.PIDFile()); current != pid || !daemon.ProcessAlive(pid) {
+				return true
+			}
+		} else if _, err := os.Stat(cfg.SocketPath); os.IsNotExist(err) {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// killDaemon escalates from SIGTERM to SIGKILL and cleans up leftover files
+func killDaemon(cfg *config.Config, pid int) error {
+	if !daemon.ProcessAlive(pid) {
+		return errcode.New(errcode.DaemonUnavailable, "no daemon PID found in %s, cannot force stop", cfg.PIDFile())
+	}
+
+	warnf("Sending SIGTERM to daemon (pid %d)", pid)
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal daemon: %w", err)
+	}
+
+	if !waitForExit(cfg, pid, stopKillGrace) {
+		warnf("Sending SIGKILL to daemon (pid %d)", pid)
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("failed to kill daemon: %w", err)
+		}
+		waitForExit(cfg, pid, stopKillGrace)
+	}
+
+	// A killed daemon can't clean up after itself
+	os.Remove(cfg.SocketPath)
+	os.Remove(cfg.PIDFile())
+
+	return printStopped(pid, true, nil)
+}
+
+// printStopped reports a stopped daemon and the worktrees it left claimed.
+// A killed daemon can't report those.
+func printStopped(pid int, forced bool, inUse []string) error {
+	if inUse == nil {
+		inUse = []string{}
+	}
+	result := map[string]interface{}{
+		"pid":              pid,
+		"forced":           forced,
+		"in_use_worktrees": inUse,
+	}
+	return printResult(result, func() error {
+		internal.PrintInfo("Daemon stopped")
+		if n := len(inUse); n > 0 {
+			internal.PrintWarn("%d worktree(s) still in use; they stay claimed until released:", n)
+			for _, id := range inUse {
+				fmt.Printf("  %s\n", id)
+			}
+		}
+		return nil
+	})
+}
@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/albertywu/gitpool/internal"
 	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
 	"github.com/spf13/cobra"
 )
 
@@ -33,7 +35,7 @@ default branch from the remote HEAD reference. If this fails, you must specify
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			client := ipc.NewClient(cfg.SocketPath)
+			client := newClient(cfg)
 			req := ipc.RepoAddRequest{
 				Name:         name,
 				Path:         path,
@@ -47,12 +49,19 @@ default branch from the remote HEAD reference. If this fails, you must specify
 			}
 
 			if !resp.Success {
-				internal.PrintError("Failed to track repository: %s", resp.Error)
-				return fmt.Errorf("track repository failed")
+				return daemonError(resp, "failed to track repository")
 			}
 
-			internal.PrintInfo("Repository '%s' tracked successfully", name)
-			return nil
+			data, _ := json.Marshal(resp.Data)
+			var tracked models.Repository
+			if err := json.Unmarshal(data, &tracked); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			return printResult(repoOutput(&tracked), func() error {
+				internal.PrintInfo("Repository '%s' tracked successfully", name)
+				return nil
+			})
 		},
 	}
 
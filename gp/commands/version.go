@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/daemon"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func NewVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Show client and daemon versions",
+		Long: `Show the version and protocol version of this gp and of the running daemon.
+
+If they differ, run 'gp restart' to replace the daemon with this gp.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadWithCustomPaths("", "", "")
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			var hello *ipc.HelloResponse
+			var helloErr error
+			running := daemon.CheckDaemonRunning(cfg.SocketPath)
+			if running {
+				hello, helloErr = ipc.NewClient(cfg.SocketPath).Hello()
+			}
+
+			// The daemon's version stays null when it isn't running or
+			// predates protocol versions
+			daemonVersion := map[string]interface{}{"running": running, "version": nil, "protocol": nil, "pid": nil}
+			if hello != nil {
+				daemonVersion["version"] = hello.Version
+				daemonVersion["protocol"] = hello.Protocol
+				daemonVersion["pid"] = hello.PID
+			}
+			result := map[string]interface{}{
+				"client": map[string]interface{}{"version": version.Version, "protocol": ipc.ProtocolVersion},
+				"daemon": daemonVersion,
+			}
+
+			err = printResult(result, func() error {
+				fmt.Printf("Client: %s (protocol %d)\n", version.Version, ipc.ProtocolVersion)
+				switch {
+				case !running:
+					fmt.Println("Daemon: not running")
+				case helloErr != nil:
+					fmt.Println("Daemon: unknown (predates protocol versions)")
+				default:
+					fmt.Printf("Daemon: %s (protocol %d, pid %d)\n", hello.Version, hello.Protocol, hello.PID)
+				}
+				return nil
+			})
+			if err != nil || !running {
+				return err
+			}
+
+			switch {
+			case helloErr != nil:
+				warnf("%v", helloErr)
+			case hello.Protocol < ipc.ProtocolVersion:
+				warnf("The daemon speaks an older protocol; some commands will fail until you run 'gp restart'")
+			case hello.Protocol > ipc.ProtocolVersion:
+				warnf("The daemon is newer than this gp; upgrade gp")
+			case hello.Version != version.Version:
+				warnf("Client and daemon versions differ; run 'gp restart' to run this gp's daemon")
+			}
+			return nil
+		},
+	}
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/albertywu/gitpool/gp/commands"
 	"github.com/albertywu/gitpool/internal"
+	"github.com/albertywu/gitpool/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -18,23 +18,41 @@ func main() {
 		Long: `gp is a CLI + daemon tool for managing a pool of pre-initialized Git worktrees.
 It enables fast, disposable checkouts for builds, tests, and CI pipelines without repeated Git fetches.
 Developers can instantly "claim" worktrees and "release" them back for reuse.`,
+		Version:           version.Version,
+		PersistentPreRunE: commands.MarkStarted,
+		// Errors are reported by ReportError, with an exit code per error code
+		SilenceErrors: true,
 	}
 
+	commands.AddOutputFlag(rootCmd)
+
 	// Add simplified top-level commands
 	rootCmd.AddCommand(commands.NewStartCmd())
 	rootCmd.AddCommand(commands.NewStopCmd())
+	rootCmd.AddCommand(commands.NewVersionCmd())
+	rootCmd.AddCommand(commands.NewRestartCmd())
+	rootCmd.AddCommand(commands.NewReloadCmd())
+	rootCmd.AddCommand(commands.NewLogsCmd())
+	rootCmd.AddCommand(commands.NewLogLevelCmd())
+	rootCmd.AddCommand(commands.NewServiceCmd())
+	rootCmd.AddCommand(commands.NewDBCmd())
 	rootCmd.AddCommand(commands.NewTrackCmd())
 	rootCmd.AddCommand(commands.NewUntrackCmd())
+	rootCmd.AddCommand(commands.NewApplyCmd())
+	rootCmd.AddCommand(commands.NewRepoCmd())
 	rootCmd.AddCommand(commands.NewClaimCmd())
 	rootCmd.AddCommand(commands.NewReleaseCmd())
 	rootCmd.AddCommand(commands.NewRefreshCmd())
 	rootCmd.AddCommand(commands.NewShowCmd())
+	rootCmd.AddCommand(commands.NewStatusCmd())
+	rootCmd.AddCommand(commands.NewEventsCmd())
+	rootCmd.AddCommand(commands.NewHistoryCmd())
+	rootCmd.AddCommand(commands.NewStatsCmd())
 
 	// Keep list command for repositories
 	rootCmd.AddCommand(commands.NewListCmd())
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		os.Exit(commands.ReportError(cmd, err))
 	}
 }
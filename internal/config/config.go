@@ -1,9 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -12,11 +18,86 @@ import (
 type Config struct {
 	ReconciliationInterval time.Duration `mapstructure:"reconciliation_interval"`
 	SocketPath             string        `mapstructure:"socket_path"`
+	ClaimRetention         time.Duration `mapstructure:"claim_retention"`
+	LogMaxSizeMB           int           `mapstructure:"log_max_size_mb"`
+	LogMaxBackups          int           `mapstructure:"log_max_backups"`
+	LogLevel               string        `mapstructure:"log_level"`
+	LogFormat              string        `mapstructure:"log_format"`
+	// Autostart lets CLI commands start a background daemon when none is
+	// running. GITPOOL_AUTOSTART overrides the config file.
+	Autostart bool `mapstructure:"autostart"`
+	// HTTPListen is the address of the optional HTTP API, e.g.
+	// 127.0.0.1:7437. Empty disables it.
+	HTTPListen string `mapstructure:"http_listen"`
+	// HTTPTokenFile holds the bearer token HTTP API clients must send;
+	// created with a random token when missing
+	HTTPTokenFile string `mapstructure:"http_token_file"`
+	// SocketGroup, a group name or ID, owns the socket so its members can
+	// use a shared daemon
+	SocketGroup string `mapstructure:"socket_group"`
+	// SocketMode holds the socket's permission bits in octal, e.g. "0660".
+	// Empty means 0600, or 0660 with a SocketGroup.
+	SocketMode string `mapstructure:"socket_mode"`
+	// AdminGroup's members may manage the daemon and other users' worktrees,
+	// like root and the daemon's own user
+	AdminGroup string `mapstructure:"admin_group"`
+	// RepoAccess limits repositories to the listed users and groups;
+	// repositories without an entry are open to everyone who can connect
+	RepoAccess map[string]RepoAccess `mapstructure:"repo_access"`
+	// Quotas cap the worktrees users and owner labels can hold at once
+	Quotas Quotas `mapstructure:"quotas"`
+	// Hooks are shell commands run in worktrees as they are created and
+	// claimed
+	Hooks Hooks `mapstructure:"hooks"`
 	// Custom paths (can be set via CLI flags)
 	ConfigDir   string `mapstructure:"-"`
 	WorktreeDir string `mapstructure:"-"`
 }
 
+// RepoAccess lists who may claim, refresh and inspect worktrees of a
+// repository, besides admins
+type RepoAccess struct {
+	Users  []string `mapstructure:"users"`
+	Groups []string `mapstructure:"groups"`
+}
+
+// QuotaLimits caps concurrent claims; zero means unlimited
+type QuotaLimits struct {
+	PerUser  int `mapstructure:"per_user"`
+	PerLabel int `mapstructure:"per_label"`
+}
+
+// Quotas holds limits on claims across all repositories and, in Repos,
+// within single repositories
+type Quotas struct {
+	PerUser  int                    `mapstructure:"per_user"`
+	PerLabel int                    `mapstructure:"per_label"`
+	Repos    map[string]QuotaLimits `mapstructure:"repos"`
+}
+
+// HookCommands holds the shell commands run at points of a worktree's life;
+// empty means none
+type HookCommands struct {
+	PostCreate string `mapstructure:"post_create"`
+	PostClaim  string `mapstructure:"post_claim"`
+}
+
+// Hooks holds the hooks of all repositories and, in Repos, those of single
+// repositories, which replace the global ones they set
+type Hooks struct {
+	PostCreate string                  `mapstructure:"post_create"`
+	PostClaim  string                  `mapstructure:"post_claim"`
+	Repos      map[string]HookCommands `mapstructure:"repos"`
+}
+
+func (h Hooks) String() string {
+	return fmt.Sprintf("post_create=%q post_claim=%q repos=%d", h.PostCreate, h.PostClaim, len(h.Repos))
+}
+
+func (q Quotas) String() string {
+	return fmt.Sprintf("per_user=%d per_label=%d repos=%d", q.PerUser, q.PerLabel, len(q.Repos))
+}
+
 func Load() (*Config, error) {
 	return LoadWithCustomPaths("", "", "")
 }
@@ -38,19 +119,10 @@ func LoadWithCustomPaths(configDir, worktreeDir, socketPath string) (*Config, er
 		configDir = GetConfigDir()
 	}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(configDir)
-
-	// Set defaults
-	viper.SetDefault("reconciliation_interval", "1m")
-
-	// Read config if exists
-	_ = viper.ReadInConfig() // Ignore error - config file is optional
-
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	// The config file is optional, but one that exists must be readable
+	cfg, _, err := load(configDir)
+	if err != nil {
+		return nil, err
 	}
 
 	// Set custom paths
@@ -68,7 +140,172 @@ func LoadWithCustomPaths(configDir, worktreeDir, socketPath string) (*Config, er
 		cfg.SocketPath = filepath.Join(cfg.WorktreeDir, "daemon.sock")
 	}
 
-	return &cfg, nil
+	return cfg, nil
+}
+
+// Reload reads the config file again for a running daemon and validates it.
+// It keeps the paths the daemon was started with. Unknown top-level keys are
+// returned so they can be reported.
+func Reload(current *Config) (*Config, []string, error) {
+	cfg, unknown, err := load(current.ConfigDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg.ConfigDir = current.ConfigDir
+	cfg.WorktreeDir = current.WorktreeDir
+	if cfg.SocketPath == "" {
+		// The running socket may come from a flag or the environment
+		cfg.SocketPath = current.SocketPath
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, unknown, nil
+}
+
+// load reads config.yaml from configDir on top of the defaults. A missing
+// file leaves the defaults; one that can't be read or parsed is an error.
+func load(configDir string) (*Config, []string, error) {
+	v := viper.New()
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(configDir)
+
+	// Set defaults
+	v.SetDefault("reconciliation_interval", "1m")
+	v.SetDefault("claim_retention", "720h")
+	v.SetDefault("log_max_size_mb", 10)
+	v.SetDefault("log_max_backups", 3)
+	v.SetDefault("log_level", "info")
+	v.SetDefault("log_format", "text")
+
+	// Read config if exists
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", ConfigFile(configDir), err)
+		}
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if value := os.Getenv("GITPOOL_AUTOSTART"); value != "" {
+		autostart, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid GITPOOL_AUTOSTART value '%s': %w", value, err)
+		}
+		cfg.Autostart = autostart
+	}
+
+	var unknown []string
+	for key := range v.AllSettings() {
+		if !knownKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	return &cfg, unknown, nil
+}
+
+// knownKeys lists the top-level settings read from config.yaml
+var knownKeys = map[string]bool{
+	"reconciliation_interval": true,
+	"socket_path":             true,
+	"claim_retention":         true,
+	"log_max_size_mb":         true,
+	"log_max_backups":         true,
+	"log_level":               true,
+	"log_format":              true,
+	"autostart":               true,
+	"http_listen":             true,
+	"http_token_file":         true,
+	"socket_group":            true,
+	"socket_mode":             true,
+	"admin_group":             true,
+	"repo_access":             true,
+	"quotas":                  true,
+	"hooks":                   true,
+	// Read by LoadRepoSpecs and applied with 'gp apply'
+	"repos": true,
+}
+
+// Validate checks settings that would make the daemon misbehave. Loading
+// doesn't validate, so that clients only needing the socket path still work;
+// the daemon and in-process clients validate before they use the settings.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.ReconciliationInterval <= 0 {
+		problems = append(problems, "reconciliation_interval must be positive")
+	}
+	if c.ClaimRetention < 0 {
+		problems = append(problems, "claim_retention must not be negative")
+	}
+	if c.LogMaxSizeMB <= 0 {
+		problems = append(problems, "log_max_size_mb must be positive")
+	}
+	if c.LogMaxBackups < 0 {
+		problems = append(problems, "log_max_backups must not be negative")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		problems = append(problems, fmt.Sprintf("log_level '%s' is not one of debug, info, warn, error", c.LogLevel))
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		problems = append(problems, fmt.Sprintf("log_format '%s' is not one of text, json", c.LogFormat))
+	}
+	if c.HTTPListen != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPListen); err != nil {
+			problems = append(problems, fmt.Sprintf("http_listen '%s' is not a host:port address", c.HTTPListen))
+		}
+	}
+	if c.Quotas.PerUser < 0 || c.Quotas.PerLabel < 0 {
+		problems = append(problems, "quotas must not be negative")
+	}
+	for repo, limits := range c.Quotas.Repos {
+		if limits.PerUser < 0 || limits.PerLabel < 0 {
+			problems = append(problems, fmt.Sprintf("quotas of repository '%s' must not be negative", repo))
+		}
+	}
+	if _, err := c.SocketFileMode(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SocketFileMode parses SocketMode; zero means the default
+func (c *Config) SocketFileMode() (os.FileMode, error) {
+	if c.SocketMode == "" {
+		return 0, nil
+	}
+	// Unquoted YAML numbers lose their leading zero, so require one to
+	// avoid reading 660 as the octal 0660 by accident
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || !strings.HasPrefix(c.SocketMode, "0") || mode&^0777 != 0 {
+		return 0, fmt.Errorf("socket_mode '%s' is not a quoted octal mode such as \"0660\"", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Access returns the allow list of a repository, if it has one
+func (c *Config) Access(repo string) (RepoAccess, bool) {
+	// Viper lowercases map keys, so repository names match case-insensitively
+	access, ok := c.RepoAccess[strings.ToLower(repo)]
+	return access, ok
+}
+
+// ConfigFile returns the path of config.yaml in configDir
+func ConfigFile(configDir string) string {
+	return filepath.Join(configDir, "config.yaml")
 }
 
 // GetWorktreeDir returns the hardcoded worktree directory
@@ -89,6 +326,35 @@ func EnsureWorktreeDir() error {
 	return nil
 }
 
+// LogFile returns the path of the daemon log used in background mode
+func (c *Config) LogFile() string {
+	return filepath.Join(c.ConfigDir, "logs", "daemon.log")
+}
+
+// LockFile returns the lock file a daemon holds for its whole lifetime so that
+// only one daemon uses the worktree directory at a time
+func (c *Config) LockFile() string {
+	return filepath.Join(c.WorktreeDir, "daemon.lock")
+}
+
+// AutostartLockFile returns the lock file clients hold while starting a daemon
+func (c *Config) AutostartLockFile() string {
+	return filepath.Join(c.WorktreeDir, "autostart.lock")
+}
+
+// TokenFile returns the file holding the HTTP API bearer token
+func (c *Config) TokenFile() string {
+	if c.HTTPTokenFile != "" {
+		return c.HTTPTokenFile
+	}
+	return filepath.Join(c.ConfigDir, "http-token")
+}
+
+// PIDFile returns the path of the daemon's PID file
+func (c *Config) PIDFile() string {
+	return filepath.Join(c.WorktreeDir, "daemon.pid")
+}
+
 // EnsureWorktreeDirForConfig ensures the worktree directory exists for a config
 func (c *Config) EnsureWorktreeDir() error {
 	if err := os.MkdirAll(c.WorktreeDir, 0755); err != nil {
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	current := &Config{
+		ConfigDir:   dir,
+		WorktreeDir: filepath.Join(dir, "worktrees"),
+		SocketPath:  filepath.Join(dir, "custom.sock"),
+	}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(ConfigFile(dir), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("reconciliation_interval: 30s\nlog_level: debug\nhooks:\n  post_claim: make deps\n  repos:\n    Web-App:\n      post_create: npm ci\nfetch_everything: true\n")
+	cfg, unknown, err := Reload(current)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if cfg.ReconciliationInterval != 30*time.Second || cfg.LogLevel != "debug" {
+		t.Errorf("Reload() = %+v, want interval 30s and level debug", cfg)
+	}
+	if cfg.SocketPath != current.SocketPath || cfg.WorktreeDir != current.WorktreeDir {
+		t.Errorf("Reload() changed paths: socket %s, worktrees %s", cfg.SocketPath, cfg.WorktreeDir)
+	}
+	if cfg.Hooks.PostClaim != "make deps" || cfg.Hooks.Repos["web-app"].PostCreate != "npm ci" {
+		t.Errorf("Reload() hooks = %+v, want post_claim and a web-app post_create", cfg.Hooks)
+	}
+	if len(unknown) != 1 || unknown[0] != "fetch_everything" {
+		t.Errorf("unknown = %v, want [fetch_everything]", unknown)
+	}
+
+	write("reconciliation_interval: 0s\nlog_format: xml\n")
+	if _, _, err := Reload(current); err == nil || !strings.Contains(err.Error(), "reconciliation_interval") || !strings.Contains(err.Error(), "log_format") {
+		t.Errorf("Reload() error = %v, want both invalid fields reported", err)
+	}
+
+	write("reconciliation_interval: [\n")
+	if _, _, err := Reload(current); err == nil {
+		t.Error("Reload() of malformed YAML succeeded, want error")
+	}
+}
+
+func TestLoadWithCustomPaths(t *testing.T) {
+	dir := t.TempDir()
+	worktrees := filepath.Join(dir, "worktrees")
+
+	// Without a config file the defaults apply
+	cfg, err := LoadWithCustomPaths(dir, worktrees, "")
+	if err != nil {
+		t.Fatalf("LoadWithCustomPaths() error = %v", err)
+	}
+	if cfg.ReconciliationInterval != time.Minute || cfg.SocketPath != filepath.Join(worktrees, "daemon.sock") {
+		t.Errorf("LoadWithCustomPaths() = %+v, want the defaults", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() of the defaults error = %v", err)
+	}
+
+	// Loading doesn't validate, but a file that can't be parsed fails
+	if err := os.WriteFile(ConfigFile(dir), []byte("reconciliation_interval: 0s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = LoadWithCustomPaths(dir, worktrees, ""); err != nil {
+		t.Fatalf("LoadWithCustomPaths() error = %v", err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "reconciliation_interval") {
+		t.Errorf("Validate() error = %v, want reconciliation_interval reported", err)
+	}
+
+	if err := os.WriteFile(ConfigFile(dir), []byte("reconciliation_interval: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWithCustomPaths(dir, worktrees, ""); err == nil {
+		t.Error("LoadWithCustomPaths() of malformed YAML succeeded, want error")
+	}
+}
+
+func TestSocketFileMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0660", 0660, false},
+		{"0600", 0600, false},
+		{"660", 0, true}, // unquoted 0660 in YAML
+		{"0888", 0, true},
+		{"01777", 0, true},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{SocketMode: tt.mode}
+		got, err := cfg.SocketFileMode()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("SocketFileMode(%q) = %v, %v, want %v, error %v", tt.mode, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRepoAccess(t *testing.T) {
+	dir := t.TempDir()
+	content := "repo_access:\n  Web-App:\n    users: [alice]\n    groups: [ci]\n"
+	if err := os.WriteFile(ConfigFile(dir), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadWithCustomPaths(dir, dir, "")
+	if err != nil {
+		t.Fatalf("LoadWithCustomPaths() error = %v", err)
+	}
+	access, ok := cfg.Access("Web-App")
+	if !ok || len(access.Users) != 1 || access.Users[0] != "alice" || len(access.Groups) != 1 || access.Groups[0] != "ci" {
+		t.Errorf("Access() = %+v, %v, want alice and ci", access, ok)
+	}
+	if _, ok := cfg.Access("other"); ok {
+		t.Error("Access() of a repository without an entry reported one")
+	}
+}
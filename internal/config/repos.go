@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxWorktrees is the pool size of a repository that doesn't set one
+const DefaultMaxWorktrees = 8
+
+// RepoSpec declares a tracked repository in the repos section of config.yaml
+type RepoSpec struct {
+	// Path is the local clone. Relative paths are resolved against the config
+	// directory; ~ expands to the home directory.
+	Path string `yaml:"path"`
+	// URL is cloned to Path if the clone doesn't exist yet
+	URL          string `yaml:"url"`
+	BaseBranch   string `yaml:"base_branch"`
+	MaxWorktrees int    `yaml:"max_worktrees"`
+	// FetchInterval makes the reconciler fetch the repository and move its
+	// idle worktrees to the tip of the base branch this often, in whole
+	// minutes. Zero leaves that to 'gp refresh'.
+	FetchInterval time.Duration `yaml:"fetch_interval"`
+}
+
+// LoadRepoSpecs reads the repos section of config.yaml, keyed by repository
+// name. It is read separately from the rest of the config so that names keep
+// their case. A missing config file declares no repositories.
+func LoadRepoSpecs(configDir string) (map[string]RepoSpec, error) {
+	path := ConfigFile(configDir)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]RepoSpec{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var file struct {
+		Repos map[string]RepoSpec `yaml:"repos"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	specs := make(map[string]RepoSpec, len(file.Repos))
+	var problems []string
+	for name, spec := range file.Repos {
+		if spec.Path == "" && spec.URL == "" {
+			problems = append(problems, fmt.Sprintf("repos.%s needs a path or url", name))
+			continue
+		}
+		if spec.MaxWorktrees < 0 {
+			problems = append(problems, fmt.Sprintf("repos.%s.max_worktrees must not be negative", name))
+			continue
+		}
+
+		if spec.FetchInterval < 0 || spec.FetchInterval%time.Minute != 0 {
+			problems = append(problems, fmt.Sprintf("repos.%s.fetch_interval must be a whole number of minutes", name))
+			continue
+		}
+
+		if spec.MaxWorktrees == 0 {
+			spec.MaxWorktrees = DefaultMaxWorktrees
+		}
+		if spec.Path == "" {
+			spec.Path = filepath.Join(configDir, "repos", name)
+		}
+		spec.Path = resolvePath(configDir, spec.Path)
+		specs[name] = spec
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid repos in %s: %s", path, strings.Join(problems, "; "))
+	}
+	return specs, nil
+}
+
+func resolvePath(base, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,152 @@
+package daemon
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// adminOnly lists the requests that manage the daemon or its repositories
+// rather than worktrees
+var adminOnly = map[ipc.MessageType]bool{
+	ipc.MessageTypeRepoAdd:    true,
+	ipc.MessageTypeRepoUpdate: true,
+	ipc.MessageTypeRepoRemove: true,
+	ipc.MessageTypeLogLevel:   true,
+	ipc.MessageTypeReload:     true,
+	ipc.MessageTypeShutdown:   true,
+	ipc.MessageTypeRestart:    true,
+}
+
+// accessPolicy decides what callers of a shared daemon may do. Admins are
+// root, the daemon's own user and members of admin_group; they may do
+// anything. A nil caller, e.g. on the HTTP API or on platforms that can't
+// identify callers, is the daemon's own user.
+type accessPolicy struct {
+	daemonUID int
+	// cfg is a copy, since Reload builds the policy from a config it still
+	// adjusts before publishing it
+	cfg config.Config
+}
+
+func newAccessPolicy(cfg *config.Config) *accessPolicy {
+	return &accessPolicy{daemonUID: os.Getuid(), cfg: *cfg}
+}
+
+// uid returns the user a caller runs as
+func (p *accessPolicy) uid(caller *ipc.Caller) int {
+	if caller == nil {
+		return p.daemonUID
+	}
+	return caller.UID
+}
+
+func (p *accessPolicy) isAdmin(caller *ipc.Caller) bool {
+	if caller == nil || caller.UID == 0 || caller.UID == p.daemonUID {
+		return true
+	}
+	return p.cfg.AdminGroup != "" && inGroup(caller, p.cfg.AdminGroup)
+}
+
+// checkRepo fails unless the caller may use the repository
+func (p *accessPolicy) checkRepo(caller *ipc.Caller, repo string) error {
+	access, ok := p.cfg.Access(repo)
+	if !ok || p.isAdmin(caller) {
+		return nil
+	}
+
+	if u, err := user.LookupId(strconv.Itoa(caller.UID)); err == nil {
+		for _, name := range access.Users {
+			if name == u.Username {
+				return nil
+			}
+		}
+	}
+	for _, group := range access.Groups {
+		if inGroup(caller, group) {
+			return nil
+		}
+	}
+	return errcode.New(errcode.PermissionDenied, "you are not allowed to use repository '%s'", repo)
+}
+
+// checkWorktree fails unless the caller may inspect or release the worktree:
+// a claimed worktree belongs to its owner, an unclaimed one to everyone who
+// may use its repository
+func (p *accessPolicy) checkWorktree(caller *ipc.Caller, wt *models.Worktree, repo string) error {
+	if p.isAdmin(caller) {
+		return nil
+	}
+	if wt.Status.HoldsBranch() && wt.OwnerUID != nil {
+		if *wt.OwnerUID == caller.UID {
+			return nil
+		}
+		return errcode.New(errcode.PermissionDenied, "worktree '%s' is claimed by another user", wt.Name)
+	}
+	return p.checkRepo(caller, repo)
+}
+
+// repoFilter returns a function reporting whether the caller may use a
+// repository, which remembers its answers since looking up users and groups
+// is slow
+func (p *accessPolicy) repoFilter(caller *ipc.Caller) func(repo string) bool {
+	if p.isAdmin(caller) {
+		return func(string) bool { return true }
+	}
+
+	var mu sync.Mutex
+	allowed := map[string]bool{}
+	return func(repo string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		ok, seen := allowed[repo]
+		if !seen {
+			ok = p.checkRepo(caller, repo) == nil
+			allowed[repo] = ok
+		}
+		return ok
+	}
+}
+
+// inGroup reports whether the caller's primary or supplementary groups
+// include group, given by name or ID
+func inGroup(caller *ipc.Caller, group string) bool {
+	gid, err := ipc.LookupGroupID(group)
+	if err != nil {
+		return false
+	}
+	if caller.GID == gid {
+		return true
+	}
+
+	u, err := user.LookupId(strconv.Itoa(caller.UID))
+	if err != nil {
+		return false
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == strconv.Itoa(gid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize restricts managing the daemon and its repositories to admins.
+// Access to individual repositories and worktrees is checked by the
+// handlers.
+func (d *Daemon) Authorize(caller *ipc.Caller, msgType ipc.MessageType) error {
+	if adminOnly[msgType] && !d.access.Load().isAdmin(caller) {
+		return errcode.New(errcode.PermissionDenied, "'%s' is restricted to admins", msgType)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/albertywu/gitpool/internal/pool"
+	"github.com/albertywu/gitpool/internal/stats"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	// Unknown to the user database, so not in any group
+	const (
+		alice = 424242
+		bob   = 434343
+	)
+	policy := newAccessPolicy(&config.Config{
+		RepoAccess: map[string]config.RepoAccess{"secret": {Users: []string{"nobody-here"}}},
+	})
+	caller := func(uid int) *ipc.Caller {
+		return &ipc.Caller{UID: uid, GID: uid, PID: 1}
+	}
+	denied := func(err error) bool {
+		return err != nil && errcode.Of(err) == errcode.PermissionDenied
+	}
+
+	if !policy.isAdmin(nil) || !policy.isAdmin(caller(os.Getuid())) || !policy.isAdmin(caller(0)) {
+		t.Error("isAdmin() = false for the daemon's own user, root or an unidentified caller")
+	}
+	if policy.isAdmin(caller(alice)) {
+		t.Error("isAdmin() = true for another user")
+	}
+
+	if err := policy.checkRepo(caller(alice), "open"); err != nil {
+		t.Errorf("checkRepo() of a repository without allow list error = %v", err)
+	}
+	if err := policy.checkRepo(caller(alice), "secret"); !denied(err) {
+		t.Errorf("checkRepo() of an allow-listed repository error = %v, want permission_denied", err)
+	}
+	if err := policy.checkRepo(nil, "secret"); err != nil {
+		t.Errorf("checkRepo() for an admin error = %v", err)
+	}
+
+	owner := alice
+	branch := "feature"
+	claimed := &models.Worktree{Name: "open-1", Status: models.WorktreeStatusInUse, Branch: &branch, OwnerUID: &owner}
+	if err := policy.checkWorktree(caller(alice), claimed, "open"); err != nil {
+		t.Errorf("checkWorktree() by the owner error = %v", err)
+	}
+	if err := policy.checkWorktree(caller(bob), claimed, "open"); !denied(err) {
+		t.Errorf("checkWorktree() by another user error = %v, want permission_denied", err)
+	}
+	if err := policy.checkWorktree(nil, claimed, "open"); err != nil {
+		t.Errorf("checkWorktree() by an admin error = %v", err)
+	}
+
+	// The owner of a released worktree no longer matters
+	idle := &models.Worktree{Name: "open-2", Status: models.WorktreeStatusIdle, OwnerUID: &owner}
+	if err := policy.checkWorktree(caller(bob), idle, "open"); err != nil {
+		t.Errorf("checkWorktree() of an idle worktree error = %v", err)
+	}
+}
+
+// TestHandlersFilterByCaller checks that read-only requests only return what
+// a caller may see: worktrees they could show, their own claims and quota
+// usage, and repositories they may use
+func TestHandlersFilterByCaller(t *testing.T) {
+	const (
+		alice = 424242
+		bob   = 434343
+	)
+	dir := t.TempDir()
+	store, err := db.NewStoreWithPath(dir)
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	defer store.Close()
+
+	d := &Daemon{
+		store:  store,
+		pool:   pool.NewPool(store, nil, dir, lockfile.NewMutex(pool.LockFile(dir))),
+		events: events.NewBus(),
+	}
+	cfg := &config.Config{
+		RepoAccess: map[string]config.RepoAccess{"secret": {Users: []string{"nobody-here"}}},
+	}
+	d.config.Store(cfg)
+	d.access.Store(newAccessPolicy(cfg))
+	d.pool.SetQuotas(pool.Quotas{QuotaLimits: pool.QuotaLimits{PerUser: 8, PerLabel: 8}})
+	labels := map[int]string{alice: "alice-ci", bob: "bob-ci"}
+
+	addWorktree := func(repo *models.Repository, name string, owner int) {
+		t.Helper()
+		wt := models.NewWorktree(repo.ID, name, filepath.Join(dir, name))
+		if owner != 0 {
+			now := time.Now()
+			wt.Status = models.WorktreeStatusInUse
+			wt.LeasedAt = &now
+			wt.Branch = &name
+			wt.OwnerUID = &owner
+		}
+		if err := store.CreateWorktree(wt); err != nil {
+			t.Fatalf("CreateWorktree() error = %v", err)
+		}
+		if owner != 0 {
+			if err := store.SetWorktreeOwner(wt.ID.String(), owner, labels[owner]); err != nil {
+				t.Fatalf("SetWorktreeOwner() error = %v", err)
+			}
+			if err := store.CreateClaim(models.NewClaim(repo, wt, name, "", "", 0)); err != nil {
+				t.Fatalf("CreateClaim() error = %v", err)
+			}
+		}
+	}
+	for _, name := range []string{"open", "secret"} {
+		repo := models.NewRepository(name, filepath.Join(dir, name), "main", 4, 0)
+		if err := store.CreateRepository(repo); err != nil {
+			t.Fatalf("CreateRepository() error = %v", err)
+		}
+		addWorktree(repo, name+"-idle", 0)
+		addWorktree(repo, name+"-alice", alice)
+		addWorktree(repo, name+"-bob", bob)
+		d.events.Publish(events.Event{Type: events.EventWorktreeCreated, Repo: name})
+	}
+
+	caller := &ipc.Caller{UID: alice, GID: alice, PID: 1}
+	sorted := func(names []string) []string {
+		sort.Strings(names)
+		return names
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name   string
+		caller *ipc.Caller
+		list   []string
+		claims []string
+		repos  []string
+		quotas []string
+	}{
+		{
+			name:   "admin",
+			list:   []string{"open-alice", "open-bob", "open-idle", "secret-alice", "secret-bob", "secret-idle"},
+			claims: []string{"open-alice", "open-bob", "secret-alice", "secret-bob"},
+			repos:  []string{"open", "secret"},
+			quotas: []string{"label alice-ci", "label bob-ci", "user 424242", "user 434343"},
+		},
+		{
+			name:   "user",
+			caller: caller,
+			list:   []string{"open-alice", "open-idle", "secret-alice"},
+			claims: []string{"open-alice", "secret-alice"},
+			repos:  []string{"open"},
+			quotas: []string{"label alice-ci", "user 424242"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []string
+			for _, detail := range d.HandleWorktreeList(ipc.WorktreeListRequest{Caller: tt.caller}).Data.([]*models.WorktreeDetail) {
+				list = append(list, detail.Worktree.Name)
+			}
+			if list = sorted(list); !equal(list, tt.list) {
+				t.Errorf("HandleWorktreeList() = %v, want %v", list, tt.list)
+			}
+
+			var claims []string
+			for _, claim := range d.HandleHistory(ipc.HistoryRequest{Caller: tt.caller}).Data.([]*models.Claim) {
+				claims = append(claims, claim.WorktreeName)
+			}
+			if claims = sorted(claims); !equal(claims, tt.claims) {
+				t.Errorf("HandleHistory() = %v, want %v", claims, tt.claims)
+			}
+
+			var repos []string
+			for _, status := range d.HandlePoolStatus(ipc.PoolStatusRequest{Caller: tt.caller}).Data.([]*models.PoolStatus) {
+				repos = append(repos, status.RepoName)
+			}
+			if repos = sorted(repos); !equal(repos, tt.repos) {
+				t.Errorf("HandlePoolStatus() = %v, want %v", repos, tt.repos)
+			}
+
+			repos = nil
+			for _, report := range d.HandleStats(ipc.StatsRequest{Caller: tt.caller}).Data.([]*stats.Report) {
+				repos = append(repos, report.RepoName)
+			}
+			if repos = sorted(repos); !equal(repos, tt.repos) {
+				t.Errorf("HandleStats() = %v, want %v", repos, tt.repos)
+			}
+
+			var status struct {
+				Quotas []models.QuotaUsage `json:"quotas"`
+			}
+			if err := json.Unmarshal(d.HandleDaemonStatus(ipc.DaemonStatusRequest{Caller: tt.caller}).Data.(json.RawMessage), &status); err != nil {
+				t.Fatalf("HandleDaemonStatus() data: %v", err)
+			}
+			var quotas []string
+			for _, usage := range status.Quotas {
+				if usage.UID != nil {
+					quotas = append(quotas, fmt.Sprintf("user %d", *usage.UID))
+				} else {
+					quotas = append(quotas, "label "+usage.Label)
+				}
+			}
+			if quotas = sorted(quotas); !equal(quotas, tt.quotas) {
+				t.Errorf("HandleDaemonStatus() quotas = %v, want %v", quotas, tt.quotas)
+			}
+
+			repos = nil
+			sub := d.HandleSubscribe(ipc.SubscribeRequest{Caller: tt.caller})
+			for _, e := range sub.Backlog {
+				repos = append(repos, e.Repo)
+			}
+			sub.Close()
+			if repos = sorted(repos); !equal(repos, tt.repos) {
+				t.Errorf("HandleSubscribe() backlog = %v, want events of %v", repos, tt.repos)
+			}
+		})
+	}
+
+	// Naming a repository the caller may not use fails rather than
+	// returning nothing
+	for name, resp := range map[string]ipc.Response{
+		"HandlePoolStatus": d.HandlePoolStatus(ipc.PoolStatusRequest{RepoName: "secret", Caller: caller}),
+		"HandleStats":      d.HandleStats(ipc.StatsRequest{RepoName: "secret", Caller: caller}),
+	} {
+		if resp.Success || resp.Code != errcode.PermissionDenied {
+			t.Errorf("%s() of a denied repository = %+v, want permission_denied", name, resp)
+		}
+	}
+}
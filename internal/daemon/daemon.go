@@ -3,121 +3,279 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/httpapi"
 	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/albertywu/gitpool/internal/pool"
 	"github.com/albertywu/gitpool/internal/repo"
+	"github.com/albertywu/gitpool/internal/stats"
+	"github.com/albertywu/gitpool/internal/version"
+	"github.com/fsnotify/fsnotify"
 )
 
 type Daemon struct {
-	config      *config.Config
+	// config is replaced as a whole on reload; never modify the loaded value
+	config      atomic.Pointer[config.Config]
 	store       *db.Store
 	repoManager *repo.Manager
 	pool        *pool.Pool
 	reconciler  *Reconciler
 	server      *ipc.Server
+	http        *httpapi.Server // nil unless http_listen is set
+	events      *events.Bus
+	lock        *lockfile.Lock
+	log         *slog.Logger
 	startTime   time.Time
 	mu          sync.RWMutex
+	access      atomic.Pointer[accessPolicy]
+
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	// handoff is set once the socket has been passed to a replacement daemon
+	handoff bool
+
+	reloadMu sync.Mutex
+	watcher  *fsnotify.Watcher
 }
 
 func New(cfg *config.Config) (*Daemon, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	socketMode, err := cfg.SocketFileMode()
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure work directory exists
 	if err := cfg.EnsureWorktreeDir(); err != nil {
 		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
 	}
 
+	// Take the instance lock before touching the database or the socket,
+	// which NewServer replaces unconditionally
+	lock, err := acquireInstanceLock(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock.WritePID(); err != nil {
+		slog.Warn("Failed to record PID in lock file", "error", err)
+	}
+
 	// Initialize database
 	store, err := db.NewStoreWithPath(cfg.WorktreeDir)
 	if err != nil {
+		lock.Release()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
 	// Initialize components
-	repoManager := repo.NewManager(store)
-	worktreePool := pool.NewPool(store)
+	bus := events.NewBus()
+	poolLock := lockfile.NewMutex(pool.LockFile(cfg.WorktreeDir))
+	repoManager := repo.NewManager(store, bus, poolLock)
+	worktreePool := pool.NewPool(store, bus, cfg.WorktreeDir, poolLock)
+	worktreePool.SetQuotas(pool.QuotasFromConfig(cfg.Quotas))
+	worktreePool.SetHooks(pool.HooksFromConfig(cfg.Hooks))
 	reconciler := NewReconciler(store, worktreePool, cfg, cfg.ReconciliationInterval)
 
 	d := &Daemon{
-		config:      cfg,
 		store:       store,
 		repoManager: repoManager,
 		pool:        worktreePool,
 		reconciler:  reconciler,
+		events:      bus,
+		lock:        lock,
+		log:         logging.Component("daemon"),
 		startTime:   time.Now(),
+		shutdownCh:  make(chan struct{}),
 	}
+	d.config.Store(cfg)
+	d.access.Store(newAccessPolicy(cfg))
 
 	// Initialize IPC server
-	server, err := ipc.NewServer(cfg.SocketPath, d)
+	server, err := ipc.NewServer(cfg.SocketPath, d, ipc.SocketOptions{
+		Mode:  socketMode,
+		Group: cfg.SocketGroup,
+	})
 	if err != nil {
 		store.Close()
+		lock.Release()
 		return nil, fmt.Errorf("failed to create IPC server: %w", err)
 	}
 	d.server = server
 
+	if cfg.HTTPListen != "" {
+		if err := d.startHTTP(); err != nil {
+			server.Close()
+			store.Close()
+			lock.Release()
+			return nil, err
+		}
+	}
+
 	return d, nil
 }
 
+// startHTTP creates the HTTP API listener, and its token file on first use
+func (d *Daemon) startHTTP() error {
+	cfg := d.config.Load()
+	tokenFile := cfg.TokenFile()
+	token, created, err := httpapi.LoadToken(tokenFile)
+	if err != nil {
+		return err
+	}
+	if created {
+		d.log.Info("Created HTTP API token", "token_file", tokenFile)
+	} else if info, err := os.Stat(tokenFile); err == nil && info.Mode().Perm()&0077 != 0 {
+		d.log.Warn("HTTP API token file is readable by other users", "token_file", tokenFile, "mode", info.Mode().Perm().String())
+	}
+
+	server, err := httpapi.NewServer(cfg.HTTPListen, token, d)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP API server: %w", err)
+	}
+	d.http = server
+	return nil
+}
+
+// stopHTTP stops the HTTP API and waits for its in-flight requests
+func (d *Daemon) stopHTTP() {
+	if d.http == nil {
+		return
+	}
+	if err := d.http.Shutdown(); err != nil {
+		d.log.Error("Failed to stop HTTP API server", "error", err)
+	}
+}
+
 func (d *Daemon) Start() error {
-	log.Printf("[INFO] Starting treefarm daemon")
-	log.Printf("[INFO] Using worktree directory: %s", d.config.WorktreeDir)
-	log.Printf("[INFO] Global reconciliation interval: %s", d.config.ReconciliationInterval)
-	log.Printf("[INFO] Listening on %s", d.config.SocketPath)
+	cfg := d.config.Load()
+	d.log.Info("Starting gitpool daemon",
+		"version", version.Version,
+		"pid", os.Getpid(),
+		"worktree_dir", cfg.WorktreeDir,
+		"reconciliation_interval", cfg.ReconciliationInterval.String(),
+		"socket", cfg.SocketPath,
+		"socket_activated", d.server.Inherited(),
+		"http_listen", cfg.HTTPListen)
+
+	if err := WritePIDFile(cfg.PIDFile()); err != nil {
+		d.log.Warn("Failed to write PID file", "error", err)
+	}
+
+	// Recover operations a crash interrupted before anything can observe
+	// their worktrees
+	if recovered, err := d.pool.Recover(); err != nil {
+		return fmt.Errorf("failed to recover interrupted operations: %w", err)
+	} else if recovered > 0 {
+		d.log.Warn("Recovered interrupted worktree operations", "count", recovered)
+	}
 
 	// Start reconciler
 	d.reconciler.Start()
 
+	// Apply config.yaml changes as they are saved
+	watcher, err := d.watchConfig()
+	if err != nil {
+		d.log.Warn("Config changes will only apply on SIGHUP or 'gp reload'", "error", err)
+	}
+	d.watcher = watcher
+
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
 
 	// Start IPC server in goroutine
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
 		if err := d.server.Serve(); err != nil {
 			errCh <- err
 		}
 	}()
-
-	// Wait for shutdown signal or error
-	select {
-	case <-sigCh:
-		log.Printf("[INFO] Received shutdown signal")
-	case err := <-errCh:
-		return fmt.Errorf("server error: %w", err)
+	if d.http != nil {
+		go func() {
+			if err := d.http.Serve(); err != nil {
+				errCh <- err
+			}
+		}()
 	}
 
-	return d.Stop()
+	// Wait for shutdown signal, shutdown request or error, reloading the
+	// config on SIGHUP
+	for {
+		select {
+		case <-hupCh:
+			d.Reload("SIGHUP")
+			continue
+		case <-sigCh:
+			d.log.Info("Received shutdown signal")
+		case <-d.shutdownCh:
+			d.log.Info("Shutdown requested")
+		case err := <-errCh:
+			d.stopHTTP()
+			RemovePIDFile(cfg.PIDFile())
+			return fmt.Errorf("server error: %w", err)
+		}
+		return d.Stop()
+	}
 }
 
 func (d *Daemon) Stop() error {
-	log.Printf("[INFO] Stopping daemon...")
+	d.log.Info("Stopping daemon")
 
-	// Stop reconciler
+	// Stop accepting requests and wait for in-flight claims and releases
+	d.stopHTTP()
+	d.server.Drain()
+
+	// Stop reconciler, waiting for a run in progress
 	d.reconciler.Stop()
 
+	if d.watcher != nil {
+		d.watcher.Close()
+	}
+
 	// Close server
 	if err := d.server.Close(); err != nil {
-		log.Printf("[ERROR] Failed to close server: %v", err)
+		d.log.Error("Failed to close server", "error", err)
 	}
 
 	// Close database
 	if err := d.store.Close(); err != nil {
-		log.Printf("[ERROR] Failed to close database: %v", err)
+		d.log.Error("Failed to close database", "error", err)
+	}
+
+	// Remove socket and PID files. A socket passed in by systemd belongs to
+	// the socket unit and keeps accepting connections for the next start.
+	cfg := d.config.Load()
+	if !d.server.Inherited() && !d.handoff {
+		os.Remove(cfg.SocketPath)
 	}
+	RemovePIDFile(cfg.PIDFile())
 
-	// Remove socket file
-	os.Remove(d.config.SocketPath)
+	// Release the instance lock last so a new daemon can't start while this
+	// one is still cleaning up
+	if err := d.lock.Release(); err != nil {
+		d.log.Error("Failed to release daemon lock", "error", err)
+	}
 
-	log.Printf("[INFO] Daemon stopped")
+	d.log.Info("Daemon stopped")
 	return nil
 }
 
@@ -127,11 +285,10 @@ func (d *Daemon) HandleRepoAdd(req ipc.RepoAddRequest) ipc.Response {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// No more fetch intervals - refresh is manual only
 	repo, err := d.repoManager.AddRepository(req.Name, req.Path, req.BaseBranch,
 		req.MaxWorktrees)
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
 	// Create initial worktrees up to the repository's max
@@ -143,7 +300,7 @@ func (d *Daemon) HandleRepoAdd(req ipc.RepoAddRequest) ipc.Response {
 func (d *Daemon) HandleRepoList() ipc.Response {
 	repos, err := d.repoManager.ListRepositories()
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
 	return ipc.Response{Success: true, Data: repos}
@@ -154,16 +311,48 @@ func (d *Daemon) HandleRepoRemove(name string) ipc.Response {
 	defer d.mu.Unlock()
 
 	if err := d.repoManager.RemoveRepository(name); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
 	return ipc.Response{Success: true}
 }
 
+func (d *Daemon) HandleRepoUpdate(req ipc.RepoUpdateRequest) ipc.Response {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	updated, err := d.repoManager.UpdateRepository(req.Name, repo.RepoUpdate{
+		MaxWorktrees:  req.MaxWorktrees,
+		BaseBranch:    req.BaseBranch,
+		FetchInterval: req.FetchInterval,
+	})
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+
+	// Resize the pool now rather than at the next scheduled run; a new
+	// base branch also moves the idle worktrees
+	if req.BaseBranch != nil {
+		d.reconciler.TriggerRebase(updated.Name)
+	} else {
+		d.reconciler.TriggerReconcile()
+	}
+
+	return ipc.Response{Success: true, Data: updated}
+}
+
 func (d *Daemon) HandleClaim(req ipc.ClaimRequest) ipc.Response {
-	worktree, err := d.pool.ClaimWorktree(req.RepoName, req.Branch)
+	access := d.access.Load()
+	if err := access.checkRepo(req.Caller, req.RepoName); err != nil {
+		return ipc.ErrorResponse(err)
+	}
+
+	worktree, err := d.pool.ClaimWorktree(req.RepoName, req.Branch, pool.ClaimOptions{
+		Owner:    req.Owner,
+		OwnerUID: access.uid(req.Caller),
+	})
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
 	// Create the claim response with both ID and path
@@ -177,23 +366,45 @@ func (d *Daemon) HandleClaim(req ipc.ClaimRequest) ipc.Response {
 }
 
 func (d *Daemon) HandleRelease(req ipc.ReleaseRequest) ipc.Response {
-	if err := d.pool.ReleaseWorktree(req.WorktreeID); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+	access := d.access.Load()
+	err := d.pool.ReleaseWorktree(req.WorktreeID, pool.ReleaseOptions{
+		Authorize: func(wt *models.Worktree, repo *models.Repository) error {
+			return access.checkWorktree(req.Caller, wt, repo.Name)
+		},
+	})
+	if err != nil {
+		return ipc.ErrorResponse(err)
 	}
 
 	return ipc.Response{Success: true}
 }
 
 func (d *Daemon) HandlePoolStatus(req ipc.PoolStatusRequest) ipc.Response {
+	access := d.access.Load()
+	if req.RepoName != "" {
+		if err := access.checkRepo(req.Caller, req.RepoName); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+	}
+
 	statuses, err := d.pool.GetPoolStatus(req.RepoName)
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
-	return ipc.Response{Success: true, Data: statuses}
+	// Only show the repositories the caller may use
+	allowed := access.repoFilter(req.Caller)
+	visible := make([]*models.PoolStatus, 0, len(statuses))
+	for _, status := range statuses {
+		if allowed(status.RepoName) {
+			visible = append(visible, status)
+		}
+	}
+
+	return ipc.Response{Success: true, Data: visible}
 }
 
-func (d *Daemon) HandleDaemonStatus() ipc.Response {
+func (d *Daemon) HandleDaemonStatus(req ipc.DaemonStatusRequest) ipc.Response {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
@@ -205,9 +416,22 @@ func (d *Daemon) HandleDaemonStatus() ipc.Response {
 		lastReconciler = &lastRun.RunTime
 	}
 
+	cfg := d.config.Load()
+	quotas, err := d.pool.QuotaUsage()
+	if err != nil {
+		d.log.Error("Failed to compute quota usage", "error", err)
+	}
+	if access := d.access.Load(); !access.isAdmin(req.Caller) {
+		quotas = d.ownQuotaUsage(quotas, access.uid(req.Caller))
+	}
+
 	status := models.DaemonStatus{
 		Running:        true,
-		SocketPath:     d.config.SocketPath,
+		Version:        version.Version,
+		PID:            os.Getpid(),
+		StartedAt:      d.startTime,
+		SocketPath:     cfg.SocketPath,
+		WorktreeDir:    cfg.WorktreeDir,
 		LastReconciler: lastReconciler,
 		Repositories:   len(repos),
 	}
@@ -215,7 +439,13 @@ func (d *Daemon) HandleDaemonStatus() ipc.Response {
 	// Convert to map for JSON response
 	statusMap := map[string]interface{}{
 		"running":         status.Running,
+		"version":         status.Version,
+		"pid":             status.PID,
+		"started_at":      status.StartedAt,
 		"socket_path":     status.SocketPath,
+		"http_listen":     cfg.HTTPListen,
+		"quotas":          quotas,
+		"worktree_dir":    status.WorktreeDir,
 		"last_reconciler": status.LastReconciler,
 		"repositories":    status.Repositories,
 		"uptime":          time.Since(d.startTime).String(),
@@ -225,34 +455,76 @@ func (d *Daemon) HandleDaemonStatus() ipc.Response {
 	return ipc.Response{Success: true, Data: json.RawMessage(data)}
 }
 
-func (d *Daemon) HandleWorktreeList() ipc.Response {
+// ownQuotaUsage keeps the usage of a user and of the owner labels they hold
+// claims under, hiding what other users hold
+func (d *Daemon) ownQuotaUsage(usage []models.QuotaUsage, uid int) []models.QuotaUsage {
+	held, err := d.store.ListHeldClaims()
+	if err != nil {
+		d.log.Error("Failed to list held claims", "error", err)
+		return []models.QuotaUsage{}
+	}
+	labels := make(map[string]bool)
+	for _, h := range held {
+		if h.OwnerUID != nil && *h.OwnerUID == uid && h.Owner != "" {
+			labels[h.Owner] = true
+		}
+	}
+
+	own := []models.QuotaUsage{}
+	for _, u := range usage {
+		if (u.UID != nil && *u.UID == uid) || (u.Scope == "label" && labels[u.Label]) {
+			own = append(own, u)
+		}
+	}
+	return own
+}
+
+func (d *Daemon) HandleWorktreeList(req ipc.WorktreeListRequest) ipc.Response {
 	details, err := d.store.ListAllWorktreesWithRepos()
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
+	}
+
+	// Only show the worktrees the caller could inspect with 'gp show'
+	access := d.access.Load()
+	visible := make([]*models.WorktreeDetail, 0, len(details))
+	for _, detail := range details {
+		if access.checkWorktree(req.Caller, detail.Worktree, detail.Repository.Name) == nil {
+			visible = append(visible, detail)
+		}
 	}
 
-	return ipc.Response{Success: true, Data: details}
+	return ipc.Response{Success: true, Data: visible}
 }
 
 func (d *Daemon) HandleRefresh(req ipc.RefreshRequest) ipc.Response {
 	// Get repository
 	repo, err := d.store.GetRepository(req.RepoName)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found", req.RepoName)}
+		return ipc.ErrorResponse(errcode.New(errcode.RepoNotFound, "repository '%s' not found", req.RepoName))
+	}
+	if err := d.access.Load().checkRepo(req.Caller, repo.Name); err != nil {
+		return ipc.ErrorResponse(err)
 	}
 
 	// Manually trigger refresh for this repository
-	log.Printf("[INFO] Manually refreshing repository '%s'", repo.Name)
+	d.log.Info("Refreshing repository", "op", "refresh", "repo", repo.Name)
+	d.events.Publish(events.Event{Type: events.EventRefreshStarted, Repo: repo.Name})
 
 	// Use the pool's ReconcileWorktrees which handles fetching and updating
 	run, err := d.pool.ReconcileWorktrees(repo)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("refresh failed: %v", err)}
+		d.events.Publish(events.Event{
+			Type: events.EventRefreshFinished,
+			Repo: repo.Name,
+			Data: map[string]interface{}{"error": err.Error()},
+		})
+		return ipc.ErrorResponse(fmt.Errorf("refresh failed: %w", err))
 	}
 
 	// Update last fetch time
 	if err := d.store.UpdateRepositoryLastFetch(repo.Name, time.Now()); err != nil {
-		log.Printf("[ERROR] Failed to update last fetch time: %v", err)
+		d.log.Error("Failed to update last fetch time", "op", "refresh", "repo", repo.Name, "error", err)
 	}
 
 	result := map[string]interface{}{
@@ -261,6 +533,15 @@ func (d *Daemon) HandleRefresh(req ipc.RefreshRequest) ipc.Response {
 		"worktrees_cleaned": run.Cleaned,
 	}
 
+	d.events.Publish(events.Event{
+		Type: events.EventRefreshFinished,
+		Repo: repo.Name,
+		Data: map[string]interface{}{
+			"worktrees_updated": run.Created,
+			"worktrees_cleaned": run.Cleaned,
+		},
+	})
+
 	return ipc.Response{Success: true, Data: result}
 }
 
@@ -271,14 +552,17 @@ func (d *Daemon) HandleShow(req ipc.ShowRequest) ipc.Response {
 		// Try by name
 		worktree, err = d.store.GetWorktreeByName(req.WorktreeID)
 		if err != nil {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("worktree '%s' not found", req.WorktreeID)}
+			return ipc.ErrorResponse(errcode.New(errcode.WorktreeNotFound, "worktree '%s' not found", req.WorktreeID))
 		}
 	}
 
 	// Get repository info
 	repo, err := d.store.GetRepositoryByID(worktree.RepoID)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("repository not found for worktree")}
+		return ipc.ErrorResponse(errcode.New(errcode.RepoNotFound, "repository not found for worktree"))
+	}
+	if err := d.access.Load().checkWorktree(req.Caller, worktree, repo.Name); err != nil {
+		return ipc.ErrorResponse(err)
 	}
 
 	// Create detail response
@@ -290,6 +574,128 @@ func (d *Daemon) HandleShow(req ipc.ShowRequest) ipc.Response {
 	return ipc.Response{Success: true, Data: detail}
 }
 
+func (d *Daemon) HandleHistory(req ipc.HistoryRequest) ipc.Response {
+	filter := models.ClaimFilter{
+		RepoName: req.RepoName,
+		Branch:   req.Branch,
+		Since:    req.Since,
+		Rejected: req.Rejected,
+		Limit:    req.Limit,
+	}
+	// Only admins see other users' claims
+	if access := d.access.Load(); !access.isAdmin(req.Caller) {
+		uid := access.uid(req.Caller)
+		filter.OwnerUID = &uid
+	}
+
+	claims, err := d.store.ListClaims(filter)
+	if err != nil {
+		return ipc.ErrorResponse(fmt.Errorf("failed to query claim history: %w", err))
+	}
+
+	return ipc.Response{Success: true, Data: claims}
+}
+
+// HandleLogLevel changes the log level until the daemon restarts and reports
+// the level in effect
+func (d *Daemon) HandleLogLevel(req ipc.LogLevelRequest) ipc.Response {
+	if req.Level != "" {
+		previous := logging.Level()
+		if err := logging.SetLevel(req.Level); err != nil {
+			return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "%v", err))
+		}
+		d.log.Info("Log level changed", "from", previous, "to", logging.Level())
+	}
+	return ipc.Response{Success: true, Data: map[string]string{"level": logging.Level()}}
+}
+
+func (d *Daemon) HandleStats(req ipc.StatsRequest) ipc.Response {
+	access := d.access.Load()
+	var repos []*models.Repository
+	if req.RepoName != "" {
+		if err := access.checkRepo(req.Caller, req.RepoName); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		repo, err := d.store.GetRepository(req.RepoName)
+		if err != nil {
+			return ipc.ErrorResponse(errcode.New(errcode.RepoNotFound, "repository '%s' not found", req.RepoName))
+		}
+		repos = []*models.Repository{repo}
+	} else {
+		var err error
+		repos, err = d.store.ListRepositories()
+		if err != nil {
+			return ipc.ErrorResponse(fmt.Errorf("failed to list repositories: %w", err))
+		}
+	}
+
+	until := time.Now()
+	allowed := access.repoFilter(req.Caller)
+	reports := make([]*stats.Report, 0, len(repos))
+	for _, repo := range repos {
+		if !allowed(repo.Name) {
+			continue
+		}
+		claims, err := d.store.ListClaims(models.ClaimFilter{
+			RepoName:    repo.Name,
+			ActiveSince: &req.Since,
+			Rejected:    true,
+		})
+		if err != nil {
+			return ipc.ErrorResponse(fmt.Errorf("failed to query claim history: %w", err))
+		}
+
+		reports = append(reports, stats.Compute(repo, claims, req.Since, until))
+	}
+
+	return ipc.Response{Success: true, Data: reports}
+}
+
+// HandleShutdown drains in-flight requests and the reconciler, reports the
+// worktrees that are still claimed and then lets Start stop the daemon
+func (d *Daemon) HandleShutdown() ipc.Response {
+	d.log.Info("Shutdown requested, waiting for in-flight requests")
+
+	d.stopHTTP()
+	d.server.Drain()
+	d.reconciler.Stop()
+
+	resp := ipc.ShutdownResponse{
+		PID:            os.Getpid(),
+		InUseWorktrees: []string{},
+	}
+
+	details, err := d.store.ListAllWorktreesWithRepos()
+	if err != nil {
+		d.log.Error("Failed to list worktrees", "error", err)
+	}
+	for _, detail := range details {
+		if detail.Worktree.Status == models.WorktreeStatusInUse {
+			resp.InUseWorktrees = append(resp.InUseWorktrees, detail.Worktree.Name)
+		}
+	}
+
+	d.requestShutdown()
+
+	data, _ := json.Marshal(resp)
+	return ipc.Response{Success: true, Data: json.RawMessage(data)}
+}
+
+// requestShutdown makes Start stop the daemon
+func (d *Daemon) requestShutdown() {
+	d.shutdownOnce.Do(func() {
+		close(d.shutdownCh)
+	})
+}
+
+func (d *Daemon) HandleSubscribe(req ipc.SubscribeRequest) *events.Subscription {
+	// Only deliver events of repositories the caller may use
+	allowed := d.access.Load().repoFilter(req.Caller)
+	return d.events.Subscribe(req.RepoName, req.Follow, func(e events.Event) bool {
+		return e.Repo == "" || allowed(e.Repo)
+	})
+}
+
 func CheckDaemonRunning(socketPath string) bool {
 	if _, err := os.Stat(socketPath); err != nil {
 		return false
@@ -1,12 +1,13 @@
 package daemon
 
 import (
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/albertywu/gitpool/internal/pool"
 	"github.com/google/uuid"
@@ -15,53 +16,134 @@ import (
 type Reconciler struct {
 	store    *db.Store
 	pool     *pool.Pool
-	config   *config.Config
-	interval time.Duration
+	log      *slog.Logger
 	stopCh   chan struct{}
+	stopOnce sync.Once
 	wg       sync.WaitGroup
+
+	// Settings that can change on config reload
+	mu        sync.Mutex
+	interval  time.Duration
+	retention time.Duration
+	resetCh   chan struct{}
+
+	// Runs requested outside the schedule, carried out by the run loop.
+	// Requests made while one is pending are merged into it.
+	triggerCh chan struct{}
+	rebases   map[string]bool
 }
 
 func NewReconciler(store *db.Store, pool *pool.Pool, cfg *config.Config, interval time.Duration) *Reconciler {
 	return &Reconciler{
-		store:    store,
-		pool:     pool,
-		config:   cfg,
-		interval: interval,
-		stopCh:   make(chan struct{}),
+		store:     store,
+		pool:      pool,
+		interval:  interval,
+		retention: cfg.ClaimRetention,
+		log:       logging.Component("reconciler"),
+		stopCh:    make(chan struct{}),
+		resetCh:   make(chan struct{}, 1),
+		triggerCh: make(chan struct{}, 1),
+		rebases:   make(map[string]bool),
 	}
 }
 
+// SetInterval changes how often the reconciler runs, starting a new period
+// from now
+func (r *Reconciler) SetInterval(interval time.Duration) {
+	r.mu.Lock()
+	r.interval = interval
+	r.mu.Unlock()
+
+	select {
+	case r.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+// SetClaimRetention changes how long finished claims are kept
+func (r *Reconciler) SetClaimRetention(retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retention = retention
+}
+
+func (r *Reconciler) settings() (interval, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.interval, r.retention
+}
+
 func (r *Reconciler) Start() {
 	r.wg.Add(1)
 	go r.run()
 }
 
+// Stop ends the reconciler loop and waits for a run in progress to finish.
+// It is safe to call more than once.
 func (r *Reconciler) Stop() {
-	close(r.stopCh)
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
 	r.wg.Wait()
 }
 
 func (r *Reconciler) run() {
 	defer r.wg.Done()
 
-	ticker := time.NewTicker(r.interval)
+	// Resume the schedule from the last run recorded in the database, so a
+	// restart doesn't cause an extra run
+	if delay := r.firstRunDelay(); delay > 0 {
+		r.log.Info("Resuming schedule from last run", "next_run_in", delay.Round(time.Second).String())
+		first := time.NewTimer(delay)
+		defer first.Stop()
+	wait:
+		for {
+			select {
+			case <-first.C:
+				break wait
+			case <-r.triggerCh:
+				r.triggered()
+			case <-r.stopCh:
+				return
+			}
+		}
+	}
+
+	interval, _ := r.settings()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	// Run immediately on start
 	r.reconcile()
 
 	for {
 		select {
 		case <-ticker.C:
 			r.reconcile()
+		case <-r.resetCh:
+			interval, _ := r.settings()
+			ticker.Reset(interval)
+		case <-r.triggerCh:
+			r.triggered()
 		case <-r.stopCh:
 			return
 		}
 	}
 }
 
+func (r *Reconciler) firstRunDelay() time.Duration {
+	last, err := r.store.GetLastReconcilerRun()
+	if err != nil {
+		return 0
+	}
+	interval, _ := r.settings()
+	if delay := interval - time.Since(last.RunTime); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
 func (r *Reconciler) reconcile() {
-	log.Printf("[INFO] Running reconciler...")
+	r.log.Debug("Running reconciler", "op", "reconcile")
 
 	totalRun := &models.ReconcilerRun{
 		ID:      uuid.New(),
@@ -73,19 +155,30 @@ func (r *Reconciler) reconcile() {
 	// Get all repositories
 	repos, err := r.store.ListRepositories()
 	if err != nil {
-		log.Printf("[ERROR] Failed to list repositories: %v", err)
+		r.log.Error("Failed to list repositories", "op", "reconcile", "error", err)
 		return
 	}
 
-	// Process each repository - only maintain worktree pool size and clean corrupt worktrees
-	// No automatic fetching - users must use 'gitpool refresh' command
+	// Process each repository - maintain worktree pool size and clean corrupt
+	// worktrees. Only repositories with a fetch interval are fetched, once it
+	// has elapsed; the others wait for 'gp refresh'.
+	now := time.Now()
 	for _, repo := range repos {
-		log.Printf("[INFO] Maintaining worktree pool for repository '%s'", repo.Name)
-
-		// Only reconcile worktree pool (create/delete), don't fetch
-		run, err := r.pool.MaintainWorktreePool(repo)
+		var run *models.ReconcilerRun
+		if repo.FetchDue(now) {
+			r.log.Debug("Fetching repository", "op", "fetch", "repo", repo.Name)
+			run, err = r.pool.ReconcileWorktrees(repo)
+			if err == nil {
+				if err := r.store.UpdateRepositoryLastFetch(repo.Name, time.Now()); err != nil {
+					r.log.Error("Failed to update last fetch time", "op", "fetch", "repo", repo.Name, "error", err)
+				}
+			}
+		} else {
+			r.log.Debug("Maintaining worktree pool", "op", "reconcile", "repo", repo.Name)
+			run, err = r.pool.MaintainWorktreePool(repo)
+		}
 		if err != nil {
-			log.Printf("[ERROR] Failed to maintain worktree pool for '%s': %v", repo.Name, err)
+			r.log.Error("Failed to maintain worktree pool", "op", "reconcile", "repo", repo.Name, "error", err)
 			continue
 		}
 
@@ -93,17 +186,59 @@ func (r *Reconciler) reconcile() {
 		totalRun.Cleaned += run.Cleaned
 	}
 
+	// Apply claim history retention
+	_, retention := r.settings()
+	if pruned, err := r.pool.PruneClaimHistory(retention); err != nil {
+		r.log.Error("Failed to prune claim history", "op", "prune", "error", err)
+	} else if pruned > 0 {
+		r.log.Info("Pruned claim history", "op", "prune", "pruned", pruned, "retention", retention.String())
+	}
+
 	// Save reconciler run
 	if err := r.store.CreateReconcilerRun(totalRun); err != nil {
-		log.Printf("[ERROR] Failed to save reconciler run: %v", err)
+		r.log.Error("Failed to save reconciler run", "op", "reconcile", "error", err)
 	}
 
 	if totalRun.Created > 0 || totalRun.Cleaned > 0 {
-		log.Printf("[INFO] Reconciler completed: created=%d, cleaned=%d",
-			totalRun.Created, totalRun.Cleaned)
+		r.log.Info("Reconciler completed", "op", "reconcile", "created", totalRun.Created, "cleaned", totalRun.Cleaned)
 	}
 }
 
+// TriggerReconcile asks for a run as soon as the reconciler is free, e.g.
+// after a repository was resized
 func (r *Reconciler) TriggerReconcile() {
-	go r.reconcile()
+	select {
+	case r.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// TriggerRebase asks for the idle worktrees of a repository to be moved to
+// its base branch, followed by a run that resizes the pool
+func (r *Reconciler) TriggerRebase(repoName string) {
+	r.mu.Lock()
+	r.rebases[repoName] = true
+	r.mu.Unlock()
+	r.TriggerReconcile()
+}
+
+// triggered carries out the rebases and the run requested since the last
+// trigger
+func (r *Reconciler) triggered() {
+	r.mu.Lock()
+	rebases := r.rebases
+	r.rebases = make(map[string]bool)
+	r.mu.Unlock()
+
+	for repoName := range rebases {
+		repo, err := r.store.GetRepository(repoName)
+		if err != nil {
+			r.log.Error("Failed to load repository for rebase", "op", "rebase", "repo", repoName, "error", err)
+			continue
+		}
+		if err := r.pool.RebaseIdleWorktrees(repo); err != nil {
+			r.log.Error("Failed to rebase idle worktrees", "op", "rebase", "repo", repoName, "error", err)
+		}
+	}
+	r.reconcile()
 }
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/models"
+	"github.com/albertywu/gitpool/internal/pool"
+	"github.com/google/uuid"
+)
+
+// TestReconcilerTriggers checks that triggered runs happen on the run loop,
+// even while it waits for the next scheduled run, and not after Stop
+func TestReconcilerTriggers(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.NewStoreWithPath(dir)
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	defer store.Close()
+
+	// A recent run puts the next scheduled one an hour away
+	seeded := time.Now()
+	if err := store.CreateReconcilerRun(&models.ReconcilerRun{ID: uuid.New(), RunTime: seeded}); err != nil {
+		t.Fatalf("CreateReconcilerRun() error = %v", err)
+	}
+	lastRun := func() time.Time {
+		t.Helper()
+		run, err := store.GetLastReconcilerRun()
+		if err != nil {
+			t.Fatalf("GetLastReconcilerRun() error = %v", err)
+		}
+		return run.RunTime
+	}
+
+	p := pool.NewPool(store, nil, dir, lockfile.NewMutex(pool.LockFile(dir)))
+	r := NewReconciler(store, p, &config.Config{}, time.Hour)
+	r.Start()
+
+	r.TriggerReconcile()
+	r.TriggerRebase("missing")
+	deadline := time.Now().Add(5 * time.Second)
+	for !lastRun().After(seeded) {
+		if time.Now().After(deadline) {
+			t.Fatal("triggered run didn't happen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r.Stop()
+	stopped := lastRun()
+	r.TriggerReconcile()
+	time.Sleep(50 * time.Millisecond)
+	if !lastRun().Equal(stopped) {
+		t.Error("triggered run happened after Stop()")
+	}
+}
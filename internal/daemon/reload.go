@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/logging"
+	"github.com/albertywu/gitpool/internal/pool"
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce groups the several events editors produce when saving
+const reloadDebounce = 250 * time.Millisecond
+
+// Reload re-reads config.yaml and applies the settings that can change while
+// the daemon runs. Invalid files and settings that need a restart are
+// reported and leave the running configuration untouched.
+func (d *Daemon) Reload(trigger string) ipc.ReloadResponse {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	resp := ipc.ReloadResponse{Applied: []string{}, Rejected: []ipc.RejectedSetting{}}
+	log := d.log.With("op", "reload", "trigger", trigger)
+
+	current := d.config.Load()
+	next, unknown, err := config.Reload(current)
+	if err != nil {
+		log.Error("Config reload rejected", "error", err)
+		resp.Rejected = append(resp.Rejected, ipc.RejectedSetting{Field: "config.yaml", Reason: err.Error()})
+		return resp
+	}
+
+	applied := func(field string, from, to interface{}) {
+		resp.Applied = append(resp.Applied, fmt.Sprintf("%s: %v -> %v", field, from, to))
+	}
+	rejected := func(field, reason string) {
+		resp.Rejected = append(resp.Rejected, ipc.RejectedSetting{Field: field, Reason: reason})
+	}
+
+	if next.ReconciliationInterval != current.ReconciliationInterval {
+		d.reconciler.SetInterval(next.ReconciliationInterval)
+		applied("reconciliation_interval", current.ReconciliationInterval, next.ReconciliationInterval)
+	}
+	if next.ClaimRetention != current.ClaimRetention {
+		d.reconciler.SetClaimRetention(next.ClaimRetention)
+		applied("claim_retention", current.ClaimRetention, next.ClaimRetention)
+	}
+	if next.LogLevel != current.LogLevel {
+		// Validate has already checked the level
+		logging.SetLevel(next.LogLevel)
+		applied("log_level", current.LogLevel, next.LogLevel)
+	}
+	if next.Autostart != current.Autostart {
+		// Only read by clients, which load the config themselves
+		applied("autostart", current.Autostart, next.Autostart)
+	}
+
+	if !reflect.DeepEqual(next.Quotas, current.Quotas) {
+		d.pool.SetQuotas(pool.QuotasFromConfig(next.Quotas))
+		applied("quotas", current.Quotas, next.Quotas)
+	}
+	if !reflect.DeepEqual(next.Hooks, current.Hooks) {
+		d.pool.SetHooks(pool.HooksFromConfig(next.Hooks))
+		applied("hooks", current.Hooks, next.Hooks)
+	}
+	if next.AdminGroup != current.AdminGroup || !reflect.DeepEqual(next.RepoAccess, current.RepoAccess) {
+		d.access.Store(newAccessPolicy(next))
+		if next.AdminGroup != current.AdminGroup {
+			applied("admin_group", current.AdminGroup, next.AdminGroup)
+		}
+		if !reflect.DeepEqual(next.RepoAccess, current.RepoAccess) {
+			applied("repo_access", fmt.Sprintf("%d entries", len(current.RepoAccess)),
+				fmt.Sprintf("%d entries", len(next.RepoAccess)))
+		}
+	}
+
+	// Settings that need a restart keep their running values, so the stored
+	// config always describes the daemon as it runs
+	const needsRestart = "takes effect after 'gp restart'"
+	if next.LogFormat != current.LogFormat {
+		rejected("log_format", needsRestart)
+		next.LogFormat = current.LogFormat
+	}
+	if next.LogMaxSizeMB != current.LogMaxSizeMB {
+		rejected("log_max_size_mb", needsRestart)
+		next.LogMaxSizeMB = current.LogMaxSizeMB
+	}
+	if next.LogMaxBackups != current.LogMaxBackups {
+		rejected("log_max_backups", needsRestart)
+		next.LogMaxBackups = current.LogMaxBackups
+	}
+	if next.SocketPath != current.SocketPath {
+		rejected("socket_path", needsRestart)
+		next.SocketPath = current.SocketPath
+	}
+	// 'gp restart' hands over the socket as it is
+	const needsNewSocket = "takes effect after 'gp stop' and 'gp start'"
+	if next.SocketGroup != current.SocketGroup {
+		rejected("socket_group", needsNewSocket)
+		next.SocketGroup = current.SocketGroup
+	}
+	if next.SocketMode != current.SocketMode {
+		rejected("socket_mode", needsNewSocket)
+		next.SocketMode = current.SocketMode
+	}
+	if next.HTTPListen != current.HTTPListen {
+		rejected("http_listen", needsRestart)
+		next.HTTPListen = current.HTTPListen
+	}
+	if next.TokenFile() != current.TokenFile() {
+		rejected("http_token_file", needsRestart)
+		next.HTTPTokenFile = current.HTTPTokenFile
+	}
+	for _, key := range unknown {
+		rejected(key, "unknown setting")
+	}
+
+	d.config.Store(next)
+
+	for _, change := range resp.Applied {
+		log.Info("Applied config change", "change", change)
+	}
+	for _, r := range resp.Rejected {
+		log.Warn("Config change not applied", "field", r.Field, "reason", r.Reason)
+	}
+	return resp
+}
+
+func (d *Daemon) HandleReload() ipc.Response {
+	resp := d.Reload("request")
+	data, _ := json.Marshal(resp)
+	return ipc.Response{Success: true, Data: json.RawMessage(data)}
+}
+
+// watchConfig reloads the config whenever config.yaml is written, created or
+// replaced, until the watcher is closed
+func (d *Daemon) watchConfig() (*fsnotify.Watcher, error) {
+	// Watch the directory, since editors often replace the file
+	configDir := d.config.Load().ConfigDir
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := watcher.Add(configDir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", configDir, err)
+	}
+
+	configFile := filepath.Clean(config.ConfigFile(configDir))
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					if timer != nil {
+						timer.Stop()
+					}
+					return
+				}
+				if filepath.Clean(event.Name) != configFile || event.Op == fsnotify.Chmod {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDebounce, func() {
+					d.Reload("file change")
+				})
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				d.log.Warn("Config watcher error", "error", err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
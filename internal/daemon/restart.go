@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/lockfile"
+)
+
+// handoffLockTimeout bounds how long a replacement daemon waits for the old
+// one to drain and release the instance lock
+const handoffLockTimeout = 2 * time.Minute
+
+// acquireInstanceLock takes the lock that keeps a second daemon away from the
+// worktree directory. A replacement daemon waits for its predecessor.
+func acquireInstanceLock(cfg *config.Config) (*lockfile.Lock, error) {
+	deadline := time.Now().Add(handoffLockTimeout)
+	for {
+		lock, err := lockfile.TryAcquire(cfg.LockFile())
+		if err == nil {
+			return lock, nil
+		}
+
+		if errors.Is(err, lockfile.ErrLocked) && ipc.HandoffPending() && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+
+		var held *lockfile.HeldError
+		if errors.As(err, &held) && held.PID > 0 {
+			return nil, fmt.Errorf("another daemon (pid %d) is already running for %s", held.PID, cfg.WorktreeDir)
+		}
+		if errors.Is(err, lockfile.ErrLocked) {
+			return nil, fmt.Errorf("another daemon is already running for %s", cfg.WorktreeDir)
+		}
+		return nil, fmt.Errorf("failed to acquire daemon lock: %w", err)
+	}
+}
+
+// HandleRestart starts a replacement daemon on the same listening socket and
+// shuts this one down. Connections arriving meanwhile wait in the socket's
+// backlog until the replacement has taken over.
+func (d *Daemon) HandleRestart(req ipc.RestartRequest) ipc.Response {
+	if os.Getenv("INVOCATION_ID") != "" || d.server.Inherited() {
+		return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "daemon is managed by systemd; use 'systemctl --user restart gitpool' instead"))
+	}
+
+	// The replacement runs as the daemon's user, so only that user or root
+	// may choose the program; admins in admin_group get the daemon's own
+	exe := req.Executable
+	if exe != "" && req.Caller != nil && req.Caller.UID != 0 && req.Caller.UID != os.Getuid() {
+		d.log.Info("Ignoring executable of restart request", "op", "restart", "uid", req.Caller.UID, "executable", exe)
+		exe = ""
+	}
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return ipc.ErrorResponse(fmt.Errorf("failed to locate gp executable: %w", err))
+		}
+		// An upgrade replaces the binary we are running from
+		exe = strings.TrimSuffix(exe, " (deleted)")
+	}
+
+	// Don't hand the socket to a binary that can't even start
+	if out, err := exec.Command(exe, "--version").CombinedOutput(); err != nil {
+		return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "new executable %s failed to run: %v %s", exe, err, strings.TrimSpace(string(out))))
+	}
+
+	file, err := d.server.Handoff()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	defer file.Close()
+
+	// The replacement starts with the arguments this daemon was started
+	// with, so it uses the same paths and log file
+	child := exec.Command(exe, os.Args[1:]...)
+	child.Env = append(os.Environ(), ipc.HandoffFDEnv+"="+strconv.Itoa(3))
+	child.ExtraFiles = []*os.File{file}
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := child.Start(); err != nil {
+		// Nothing was handed over, so stop gracefully rather than leave a
+		// half-configured listener behind
+		d.log.Error("Failed to start replacement daemon", "op", "restart", "error", err)
+		d.handoff = false
+		d.requestShutdown()
+		return ipc.ErrorResponse(fmt.Errorf("failed to start new daemon: %w; daemon is shutting down", err))
+	}
+	go child.Wait()
+
+	d.log.Info("Handed socket to new daemon, draining", "op", "restart", "new_pid", child.Process.Pid)
+	d.handoff = true
+	d.requestShutdown()
+
+	resp := ipc.RestartResponse{OldPID: os.Getpid(), NewPID: child.Process.Pid}
+	data, _ := json.Marshal(resp)
+	return ipc.Response{Success: true, Data: json.RawMessage(data)}
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one numbered schema change. Migrations run in order, each in
+// its own transaction, and are recorded in schema_migrations. Never edit or
+// renumber a migration once released; add a new one instead.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "initial schema", migrateInitialSchema},
+	{2, "repositories.last_fetch_time", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "repositories", "last_fetch_time", "TIMESTAMP")
+	}},
+	{3, "worktrees.branch", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "worktrees", "branch", "TEXT")
+	}},
+	{4, "claims.claim_latency_ms", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "claims", "claim_latency_ms", "INTEGER")
+	}},
+	{5, "worktrees.owner_uid", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "worktrees", "owner_uid", "INTEGER")
+	}},
+	{6, "claims.owner_uid", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "claims", "owner_uid", "INTEGER")
+	}},
+	{7, "worktrees.owner", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "worktrees", "owner", "TEXT NOT NULL DEFAULT ''")
+	}},
+}
+
+// SchemaVersion is the newest schema version this binary knows
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStatus describes one known migration and whether it was applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// The tables are created with IF NOT EXISTS so that databases created before
+// schema_migrations existed are adopted at version 1 and brought up to date
+// by the later migrations.
+func migrateInitialSchema(tx *sql.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS repositories (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			path TEXT NOT NULL,
+			max_worktrees INTEGER NOT NULL,
+			default_branch TEXT NOT NULL,
+			fetch_interval INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS worktrees (
+			id TEXT PRIMARY KEY,
+			repo_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			path TEXT NOT NULL,
+			status TEXT NOT NULL,
+			leased_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (repo_id) REFERENCES repositories(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS reconciler_runs (
+			id TEXT PRIMARY KEY,
+			run_time TIMESTAMP NOT NULL,
+			created INTEGER NOT NULL,
+			cleaned INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS claims (
+			id TEXT PRIMARY KEY,
+			worktree_id TEXT NOT NULL,
+			worktree_name TEXT NOT NULL,
+			repo_id TEXT NOT NULL,
+			repo_name TEXT NOT NULL,
+			branch TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			claimed_at TIMESTAMP NOT NULL,
+			released_at TIMESTAMP,
+			start_sha TEXT NOT NULL,
+			end_sha TEXT,
+			outcome TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_worktrees_repo_id ON worktrees(repo_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_worktrees_status ON worktrees(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_claims_repo_name ON claims(repo_name)`,
+		`CREATE INDEX IF NOT EXISTS idx_claims_claimed_at ON claims(claimed_at)`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column unless the table already has it
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (s *Store) ensureMigrationsTable() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	return err
+}
+
+func (s *Store) appliedMigrations() (map[int]time.Time, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// checkSchemaVersion refuses databases written by a newer gp, whose schema
+// this binary can't safely use
+func checkSchemaVersion(applied map[int]time.Time) error {
+	for version := range applied {
+		if version > SchemaVersion() {
+			return fmt.Errorf("database schema version %d is newer than this gp supports (%d); upgrade gp", version, SchemaVersion())
+		}
+	}
+	return nil
+}
+
+// Migrate applies all pending migrations in order and returns the ones it
+// applied
+func (s *Store) Migrate() ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return nil, err
+	}
+
+	var ran []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		now := time.Now()
+		applied, err := s.runMigration(m, now)
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		if applied {
+			ran = append(ran, MigrationStatus{Version: m.version, Name: m.name, AppliedAt: &now})
+		}
+	}
+
+	return ran, nil
+}
+
+// runMigration applies a migration unless another process applied it since
+// Migrate looked, which the transaction's write lock makes safe to check
+func (s *Store) runMigration(m migration, now time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done > 0 {
+		return false, nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, now); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// MigrationStatus lists every migration this binary knows and when it was
+// applied, if it was
+func (s *Store) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name}
+		if appliedAt, ok := applied[m.version]; ok {
+			appliedAt := appliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("fresh database", func(t *testing.T) {
+		store, err := NewStoreWithPath(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewStoreWithPath() error = %v", err)
+		}
+		defer store.Close()
+
+		statuses, err := store.MigrationStatus()
+		if err != nil {
+			t.Fatalf("MigrationStatus() error = %v", err)
+		}
+		if len(statuses) != SchemaVersion() {
+			t.Fatalf("got %d migrations, want %d", len(statuses), SchemaVersion())
+		}
+		for _, m := range statuses {
+			if m.AppliedAt == nil {
+				t.Errorf("migration %d not applied", m.Version)
+			}
+		}
+
+		applied, err := store.Migrate()
+		if err != nil || len(applied) != 0 {
+			t.Errorf("second Migrate() = %v, %v, want nothing applied", applied, err)
+		}
+	})
+
+	t.Run("database without schema_migrations", func(t *testing.T) {
+		dir := t.TempDir()
+		raw, err := sql.Open("sqlite3", DatabasePath(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// An early schema: worktrees already has branch, claims lacks
+		// claim_latency_ms
+		for _, query := range []string{
+			`CREATE TABLE repositories (id TEXT PRIMARY KEY, name TEXT UNIQUE NOT NULL, path TEXT NOT NULL,
+				max_worktrees INTEGER NOT NULL, default_branch TEXT NOT NULL, fetch_interval INTEGER NOT NULL,
+				created_at TIMESTAMP NOT NULL)`,
+			`CREATE TABLE worktrees (id TEXT PRIMARY KEY, repo_id TEXT NOT NULL, name TEXT NOT NULL,
+				path TEXT NOT NULL, status TEXT NOT NULL, leased_at TIMESTAMP, branch TEXT, created_at TIMESTAMP NOT NULL)`,
+			`CREATE TABLE claims (id TEXT PRIMARY KEY, worktree_id TEXT NOT NULL, worktree_name TEXT NOT NULL,
+				repo_id TEXT NOT NULL, repo_name TEXT NOT NULL, branch TEXT NOT NULL, owner TEXT NOT NULL,
+				claimed_at TIMESTAMP NOT NULL, released_at TIMESTAMP, start_sha TEXT NOT NULL, end_sha TEXT,
+				outcome TEXT NOT NULL)`,
+		} {
+			if _, err := raw.Exec(query); err != nil {
+				t.Fatal(err)
+			}
+		}
+		raw.Close()
+
+		store, err := NewStoreWithPath(dir)
+		if err != nil {
+			t.Fatalf("NewStoreWithPath() error = %v", err)
+		}
+		defer store.Close()
+
+		if _, err := store.db.Exec(`SELECT claim_latency_ms FROM claims`); err != nil {
+			t.Errorf("claim_latency_ms not added: %v", err)
+		}
+		if _, err := store.db.Exec(`SELECT last_fetch_time FROM repositories`); err != nil {
+			t.Errorf("last_fetch_time not added: %v", err)
+		}
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewStoreWithPath(dir)
+		if err != nil {
+			t.Fatalf("NewStoreWithPath() error = %v", err)
+		}
+		if _, err := store.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			SchemaVersion()+1, "from the future", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		store.Close()
+
+		_, err = NewStoreWithPath(dir)
+		if err == nil || !strings.Contains(err.Error(), "newer than this gp supports") {
+			t.Errorf("NewStoreWithPath() error = %v, want newer schema refusal", err)
+		}
+	})
+}
@@ -7,13 +7,27 @@ import (
 	"time"
 
 	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/errcode"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrStateChanged is returned by compare-and-set updates when the row is no
+// longer in the expected state, e.g. because another process changed it
+var ErrStateChanged = errcode.New(errcode.Conflict, "worktree state changed concurrently")
+
+// dbtx is the part of *sql.DB and *sql.Tx the queries need, so the same
+// methods run inside and outside a transaction
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type Store struct {
 	db *sql.DB
+	q  dbtx
 }
 
 func NewStore() (*Store, error) {
@@ -21,83 +35,71 @@ func NewStore() (*Store, error) {
 }
 
 func NewStoreWithPath(worktreeDir string) (*Store, error) {
-	dbPath := filepath.Join(worktreeDir, "gitpool.db")
-	db, err := sql.Open("sqlite3", dbPath)
+	store, err := OpenStoreWithPath(worktreeDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	store := &Store{db: db}
-	if err := store.migrate(); err != nil {
-		db.Close()
+	if _, err := store.Migrate(); err != nil {
+		store.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return store, nil
 }
 
+// OpenStoreWithPath opens the database without migrating it, e.g. to report
+// or run migrations explicitly
+func OpenStoreWithPath(worktreeDir string) (*Store, error) {
+	db, err := sql.Open("sqlite3", dsn(DatabasePath(worktreeDir)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &Store{db: db, q: db}, nil
+}
+
+// dsn enables WAL so readers don't block the writer, waits for locks held by
+// other processes instead of failing with SQLITE_BUSY, and starts
+// transactions with BEGIN IMMEDIATE so they take the write lock up front
+func dsn(path string) string {
+	return "file:" + path + "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+}
+
+// DatabasePath returns the location of the database in a worktree directory
+func DatabasePath(worktreeDir string) string {
+	return filepath.Join(worktreeDir, "gitpool.db")
+}
+
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-func (s *Store) migrate() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS repositories (
-			id TEXT PRIMARY KEY,
-			name TEXT UNIQUE NOT NULL,
-			path TEXT NOT NULL,
-			max_worktrees INTEGER NOT NULL,
-			default_branch TEXT NOT NULL,
-			fetch_interval INTEGER NOT NULL,
-			created_at TIMESTAMP NOT NULL
-		)`,
-		`CREATE TABLE IF NOT EXISTS worktrees (
-			id TEXT PRIMARY KEY,
-			repo_id TEXT NOT NULL,
-			name TEXT NOT NULL,
-			path TEXT NOT NULL,
-			status TEXT NOT NULL,
-			leased_at TIMESTAMP,
-			branch TEXT,
-			created_at TIMESTAMP NOT NULL,
-			FOREIGN KEY (repo_id) REFERENCES repositories(id) ON DELETE CASCADE
-		)`,
-		`CREATE TABLE IF NOT EXISTS reconciler_runs (
-			id TEXT PRIMARY KEY,
-			run_time TIMESTAMP NOT NULL,
-			created INTEGER NOT NULL,
-			cleaned INTEGER NOT NULL
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_worktrees_repo_id ON worktrees(repo_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_worktrees_status ON worktrees(status)`,
-		// Add last_fetch_time column to repositories table (safe if column already exists)
-		`ALTER TABLE repositories ADD COLUMN last_fetch_time TIMESTAMP`,
-		// Add branch column to worktrees table (safe if column already exists)
-		`ALTER TABLE worktrees ADD COLUMN branch TEXT`,
-	}
-
-	for _, query := range queries {
-		if _, err := s.db.Exec(query); err != nil {
-			// Ignore "duplicate column name" error for ALTER TABLE statements
-			if query[:11] == "ALTER TABLE" &&
-				(err.Error() == "duplicate column name: last_fetch_time" ||
-					err.Error() == "SQL logic error: duplicate column name: last_fetch_time" ||
-					err.Error() == "duplicate column name: branch" ||
-					err.Error() == "SQL logic error: duplicate column name: branch") {
-				continue
-			}
-			return fmt.Errorf("migration failed: %w", err)
-		}
+// WithTx runs fn with a Store whose methods all run in one transaction. The
+// transaction commits if fn returns nil and rolls back otherwise. Calling
+// WithTx on a transactional Store reuses its transaction.
+func (s *Store) WithTx(fn func(tx *Store) error) error {
+	if _, ok := s.q.(*sql.Tx); ok {
+		return fn(s)
 	}
 
-	return nil
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Store{db: s.db, q: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Repository methods
 func (s *Store) CreateRepository(repo *models.Repository) error {
 	query := `INSERT INTO repositories (id, name, path, max_worktrees, default_branch, fetch_interval, last_fetch_time, created_at)
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, repo.ID.String(), repo.Name, repo.Path, repo.MaxWorktrees,
+	_, err := s.q.Exec(query, repo.ID.String(), repo.Name, repo.Path, repo.MaxWorktrees,
 		repo.BaseBranch, repo.FetchInterval, repo.LastFetchTime, repo.CreatedAt)
 	return err
 }
@@ -105,7 +107,7 @@ func (s *Store) CreateRepository(repo *models.Repository) error {
 func (s *Store) GetRepository(name string) (*models.Repository, error) {
 	query := `SELECT id, name, path, max_worktrees, default_branch, fetch_interval, last_fetch_time, created_at 
 			  FROM repositories WHERE name = ?`
-	row := s.db.QueryRow(query, name)
+	row := s.q.QueryRow(query, name)
 
 	var repo models.Repository
 	var idStr string
@@ -126,7 +128,7 @@ func (s *Store) GetRepository(name string) (*models.Repository, error) {
 func (s *Store) GetRepositoryByID(id uuid.UUID) (*models.Repository, error) {
 	query := `SELECT id, name, path, max_worktrees, default_branch, fetch_interval, last_fetch_time, created_at 
 			  FROM repositories WHERE id = ?`
-	row := s.db.QueryRow(query, id.String())
+	row := s.q.QueryRow(query, id.String())
 
 	var repo models.Repository
 	var idStr string
@@ -147,7 +149,7 @@ func (s *Store) GetRepositoryByID(id uuid.UUID) (*models.Repository, error) {
 func (s *Store) ListRepositories() ([]*models.Repository, error) {
 	query := `SELECT id, name, path, max_worktrees, default_branch, fetch_interval, last_fetch_time, created_at 
 			  FROM repositories ORDER BY name`
-	rows, err := s.db.Query(query)
+	rows, err := s.q.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -176,13 +178,27 @@ func (s *Store) ListRepositories() ([]*models.Repository, error) {
 
 func (s *Store) DeleteRepository(name string) error {
 	query := `DELETE FROM repositories WHERE name = ?`
-	_, err := s.db.Exec(query, name)
+	_, err := s.q.Exec(query, name)
 	return err
 }
 
+// UpdateRepository saves a repository's pool size, base branch and fetch
+// interval
+func (s *Store) UpdateRepository(repo *models.Repository) error {
+	query := `UPDATE repositories SET max_worktrees = ?, default_branch = ?, fetch_interval = ? WHERE id = ?`
+	result, err := s.q.Exec(query, repo.MaxWorktrees, repo.BaseBranch, repo.FetchInterval, repo.ID.String())
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("repository '%s' not found", repo.Name)
+	}
+	return nil
+}
+
 func (s *Store) UpdateRepositoryLastFetch(name string, lastFetchTime time.Time) error {
 	query := `UPDATE repositories SET last_fetch_time = ? WHERE name = ?`
-	_, err := s.db.Exec(query, lastFetchTime, name)
+	_, err := s.q.Exec(query, lastFetchTime, name)
 	return err
 }
 
@@ -190,20 +206,20 @@ func (s *Store) UpdateRepositoryLastFetch(name string, lastFetchTime time.Time)
 func (s *Store) CreateWorktree(worktree *models.Worktree) error {
 	query := `INSERT INTO worktrees (id, repo_id, name, path, status, leased_at, branch, created_at)
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, worktree.ID.String(), worktree.RepoID.String(), worktree.Name,
+	_, err := s.q.Exec(query, worktree.ID.String(), worktree.RepoID.String(), worktree.Name,
 		worktree.Path, worktree.Status, worktree.LeasedAt, worktree.Branch, worktree.CreatedAt)
 	return err
 }
 
 func (s *Store) GetWorktree(id string) (*models.Worktree, error) {
-	query := `SELECT id, repo_id, name, path, status, leased_at, branch, created_at 
+	query := `SELECT id, repo_id, name, path, status, leased_at, branch, owner_uid, created_at 
 			  FROM worktrees WHERE id = ?`
-	row := s.db.QueryRow(query, id)
+	row := s.q.QueryRow(query, id)
 
 	var worktree models.Worktree
 	var idStr, repoIDStr string
 	err := row.Scan(&idStr, &repoIDStr, &worktree.Name, &worktree.Path,
-		&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.CreatedAt)
+		&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.OwnerUID, &worktree.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -215,14 +231,14 @@ func (s *Store) GetWorktree(id string) (*models.Worktree, error) {
 }
 
 func (s *Store) GetWorktreeByName(name string) (*models.Worktree, error) {
-	query := `SELECT id, repo_id, name, path, status, leased_at, branch, created_at 
+	query := `SELECT id, repo_id, name, path, status, leased_at, branch, owner_uid, created_at 
 			  FROM worktrees WHERE name = ?`
-	row := s.db.QueryRow(query, name)
+	row := s.q.QueryRow(query, name)
 
 	var worktree models.Worktree
 	var idStr, repoIDStr string
 	err := row.Scan(&idStr, &repoIDStr, &worktree.Name, &worktree.Path,
-		&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.CreatedAt)
+		&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.OwnerUID, &worktree.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -234,9 +250,9 @@ func (s *Store) GetWorktreeByName(name string) (*models.Worktree, error) {
 }
 
 func (s *Store) ListWorktreesByRepo(repoID uuid.UUID) ([]*models.Worktree, error) {
-	query := `SELECT id, repo_id, name, path, status, leased_at, branch, created_at 
+	query := `SELECT id, repo_id, name, path, status, leased_at, branch, owner_uid, created_at 
 			  FROM worktrees WHERE repo_id = ?`
-	rows, err := s.db.Query(query, repoID.String())
+	rows, err := s.q.Query(query, repoID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -246,9 +262,9 @@ func (s *Store) ListWorktreesByRepo(repoID uuid.UUID) ([]*models.Worktree, error
 }
 
 func (s *Store) ListIdleWorktreesByRepo(repoID uuid.UUID) ([]*models.Worktree, error) {
-	query := `SELECT id, repo_id, name, path, status, leased_at, branch, created_at 
+	query := `SELECT id, repo_id, name, path, status, leased_at, branch, owner_uid, created_at 
 			  FROM worktrees WHERE repo_id = ? AND status = ?`
-	rows, err := s.db.Query(query, repoID.String(), models.WorktreeStatusIdle)
+	rows, err := s.q.Query(query, repoID.String(), models.WorktreeStatusIdle)
 	if err != nil {
 		return nil, err
 	}
@@ -257,22 +273,33 @@ func (s *Store) ListIdleWorktreesByRepo(repoID uuid.UUID) ([]*models.Worktree, e
 	return s.scanWorktrees(rows)
 }
 
-func (s *Store) UpdateWorktreeStatus(id string, status models.WorktreeStatus, leasedAt *time.Time) error {
-	query := `UPDATE worktrees SET status = ?, leased_at = ? WHERE id = ?`
-	_, err := s.db.Exec(query, status, leasedAt, id)
-	return err
+// TransitionWorktree moves a worktree from one status to another, setting its
+// lease time and branch, only if it is still in the from status. It returns
+// ErrStateChanged otherwise.
+func (s *Store) TransitionWorktree(id string, from, to models.WorktreeStatus, leasedAt *time.Time, branch *string) error {
+	query := `UPDATE worktrees SET status = ?, leased_at = ?, branch = ? WHERE id = ? AND status = ?`
+	result, err := s.q.Exec(query, to, leasedAt, branch, id, from)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
 }
 
-func (s *Store) UpdateWorktreeStatusAndBranch(id string, status models.WorktreeStatus, leasedAt *time.Time, branch *string) error {
-	query := `UPDATE worktrees SET status = ?, leased_at = ?, branch = ? WHERE id = ?`
-	_, err := s.db.Exec(query, status, leasedAt, branch, id)
-	return err
+// SetWorktreeOwner records the user and owner label claiming a worktree
+func (s *Store) SetWorktreeOwner(id string, uid int, owner string) error {
+	query := `UPDATE worktrees SET owner_uid = ?, owner = ? WHERE id = ?`
+	result, err := s.q.Exec(query, uid, owner, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
 }
 
 func (s *Store) IsBranchInUseForRepo(repoID uuid.UUID, branch string) (bool, error) {
-	query := `SELECT COUNT(*) FROM worktrees WHERE repo_id = ? AND branch = ? AND status = ?`
+	query := `SELECT COUNT(*) FROM worktrees WHERE repo_id = ? AND branch = ? AND status IN (?, ?, ?)`
 	var count int
-	err := s.db.QueryRow(query, repoID.String(), branch, models.WorktreeStatusInUse).Scan(&count)
+	err := s.q.QueryRow(query, repoID.String(), branch, models.WorktreeStatusInUse,
+		models.WorktreeStatusClaiming, models.WorktreeStatusReleasing).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -281,7 +308,14 @@ func (s *Store) IsBranchInUseForRepo(repoID uuid.UUID, branch string) (bool, err
 
 func (s *Store) DeleteWorktree(id string) error {
 	query := `DELETE FROM worktrees WHERE id = ?`
-	_, err := s.db.Exec(query, id)
+	_, err := s.q.Exec(query, id)
+	return err
+}
+
+// DeleteWorktreesByRepo deletes all worktree rows of a repository
+func (s *Store) DeleteWorktreesByRepo(repoID uuid.UUID) error {
+	query := `DELETE FROM worktrees WHERE repo_id = ?`
+	_, err := s.q.Exec(query, repoID.String())
 	return err
 }
 
@@ -289,7 +323,7 @@ func (s *Store) DeleteWorktree(id string) error {
 func (s *Store) ListAllWorktreesWithRepos() ([]*models.WorktreeDetail, error) {
 	query := `
 		SELECT 
-			w.id, w.repo_id, w.name, w.path, w.status, w.leased_at, w.branch, w.created_at,
+			w.id, w.repo_id, w.name, w.path, w.status, w.leased_at, w.branch, w.owner_uid, w.created_at,
 			r.id, r.name, r.path, r.max_worktrees, r.default_branch, r.last_fetch_time, 
 			r.fetch_interval, r.created_at
 		FROM worktrees w
@@ -299,7 +333,7 @@ func (s *Store) ListAllWorktreesWithRepos() ([]*models.WorktreeDetail, error) {
 			w.created_at DESC,  -- Newest first
 			r.name
 	`
-	rows, err := s.db.Query(query)
+	rows, err := s.q.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +348,7 @@ func (s *Store) ListAllWorktreesWithRepos() ([]*models.WorktreeDetail, error) {
 
 		err := rows.Scan(
 			&wIDStr, &wRepoIDStr, &worktree.Name, &worktree.Path,
-			&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.CreatedAt,
+			&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.OwnerUID, &worktree.CreatedAt,
 			&rIDStr, &repo.Name, &repo.Path, &repo.MaxWorktrees,
 			&repo.BaseBranch, &repo.LastFetchTime, &repo.FetchInterval,
 			&repo.CreatedAt,
@@ -335,9 +369,32 @@ func (s *Store) ListAllWorktreesWithRepos() ([]*models.WorktreeDetail, error) {
 	return details, rows.Err()
 }
 
+// ListHeldClaims returns who holds each worktree that has a branch claimed
+func (s *Store) ListHeldClaims() ([]models.HeldClaim, error) {
+	query := `SELECT r.name, w.owner_uid, w.owner FROM worktrees w
+			  JOIN repositories r ON w.repo_id = r.id
+			  WHERE w.status IN (?, ?, ?)`
+	rows, err := s.q.Query(query, models.WorktreeStatusInUse, models.WorktreeStatusClaiming,
+		models.WorktreeStatusReleasing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var held []models.HeldClaim
+	for rows.Next() {
+		var h models.HeldClaim
+		if err := rows.Scan(&h.RepoName, &h.OwnerUID, &h.Owner); err != nil {
+			return nil, err
+		}
+		held = append(held, h)
+	}
+	return held, rows.Err()
+}
+
 func (s *Store) CountWorktreesByStatus(repoID uuid.UUID) (map[models.WorktreeStatus]int, error) {
 	query := `SELECT status, COUNT(*) FROM worktrees WHERE repo_id = ? GROUP BY status`
-	rows, err := s.db.Query(query, repoID.String())
+	rows, err := s.q.Query(query, repoID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -356,16 +413,109 @@ func (s *Store) CountWorktreesByStatus(repoID uuid.UUID) (map[models.WorktreeSta
 	return counts, rows.Err()
 }
 
+// Claim history methods
+func (s *Store) CreateClaim(claim *models.Claim) error {
+	query := `INSERT INTO claims (id, worktree_id, worktree_name, repo_id, repo_name, branch, owner,
+			  owner_uid, claimed_at, released_at, start_sha, end_sha, outcome, claim_latency_ms)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.q.Exec(query, claim.ID.String(), claim.WorktreeID.String(), claim.WorktreeName,
+		claim.RepoID.String(), claim.RepoName, claim.Branch, claim.Owner, claim.OwnerUID, claim.ClaimedAt,
+		claim.ReleasedAt, claim.StartSHA, claim.EndSHA, claim.Outcome, claim.LatencyMS)
+	return err
+}
+
+// FinishClaim closes the active claim on a worktree
+func (s *Store) FinishClaim(worktreeID uuid.UUID, releasedAt time.Time, endSHA *string, outcome models.ClaimOutcome) error {
+	query := `UPDATE claims SET released_at = ?, end_sha = ?, outcome = ?
+			  WHERE worktree_id = ? AND outcome = ?`
+	_, err := s.q.Exec(query, releasedAt, endSHA, outcome, worktreeID.String(), models.ClaimOutcomeActive)
+	return err
+}
+
+func (s *Store) ListClaims(filter models.ClaimFilter) ([]*models.Claim, error) {
+	query := `SELECT id, worktree_id, worktree_name, repo_id, repo_name, branch, owner, owner_uid,
+			  claimed_at, released_at, start_sha, end_sha, outcome, claim_latency_ms
+			  FROM claims WHERE 1 = 1`
+	var args []interface{}
+
+	if filter.RepoName != "" {
+		query += ` AND repo_name = ?`
+		args = append(args, filter.RepoName)
+	}
+	if filter.Branch != "" {
+		query += ` AND branch = ?`
+		args = append(args, filter.Branch)
+	}
+	if filter.Since != nil {
+		query += ` AND claimed_at >= ?`
+		args = append(args, *filter.Since)
+	}
+	if filter.ActiveSince != nil {
+		query += ` AND (released_at IS NULL OR released_at >= ?)`
+		args = append(args, *filter.ActiveSince)
+	}
+	if filter.OwnerUID != nil {
+		query += ` AND owner_uid = ?`
+		args = append(args, *filter.OwnerUID)
+	}
+	if !filter.Rejected {
+		query += ` AND outcome != ?`
+		args = append(args, models.ClaimOutcomeRejected)
+	}
+
+	query += ` ORDER BY claimed_at DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []*models.Claim
+	for rows.Next() {
+		var claim models.Claim
+		var idStr, worktreeIDStr, repoIDStr string
+		err := rows.Scan(&idStr, &worktreeIDStr, &claim.WorktreeName, &repoIDStr, &claim.RepoName,
+			&claim.Branch, &claim.Owner, &claim.OwnerUID, &claim.ClaimedAt, &claim.ReleasedAt, &claim.StartSHA,
+			&claim.EndSHA, &claim.Outcome, &claim.LatencyMS)
+		if err != nil {
+			return nil, err
+		}
+
+		claim.ID, _ = uuid.Parse(idStr)
+		claim.WorktreeID, _ = uuid.Parse(worktreeIDStr)
+		claim.RepoID, _ = uuid.Parse(repoIDStr)
+
+		claims = append(claims, &claim)
+	}
+
+	return claims, rows.Err()
+}
+
+// PruneClaims deletes finished claims released before the given time
+func (s *Store) PruneClaims(before time.Time) (int64, error) {
+	query := `DELETE FROM claims WHERE outcome != ? AND released_at < ?`
+	result, err := s.q.Exec(query, models.ClaimOutcomeActive, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // Reconciler methods
 func (s *Store) CreateReconcilerRun(run *models.ReconcilerRun) error {
 	query := `INSERT INTO reconciler_runs (id, run_time, created, cleaned) VALUES (?, ?, ?, ?)`
-	_, err := s.db.Exec(query, run.ID.String(), run.RunTime, run.Created, run.Cleaned)
+	_, err := s.q.Exec(query, run.ID.String(), run.RunTime, run.Created, run.Cleaned)
 	return err
 }
 
 func (s *Store) GetLastReconcilerRun() (*models.ReconcilerRun, error) {
 	query := `SELECT id, run_time, created, cleaned FROM reconciler_runs ORDER BY run_time DESC LIMIT 1`
-	row := s.db.QueryRow(query)
+	row := s.q.QueryRow(query)
 
 	var run models.ReconcilerRun
 	var idStr string
@@ -385,7 +535,7 @@ func (s *Store) scanWorktrees(rows *sql.Rows) ([]*models.Worktree, error) {
 		var worktree models.Worktree
 		var idStr, repoIDStr string
 		err := rows.Scan(&idStr, &repoIDStr, &worktree.Name, &worktree.Path,
-			&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.CreatedAt)
+			&worktree.Status, &worktree.LeasedAt, &worktree.Branch, &worktree.OwnerUID, &worktree.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -398,3 +548,14 @@ func (s *Store) scanWorktrees(rows *sql.Rows) ([]*models.Worktree, error) {
 
 	return worktrees, rows.Err()
 }
+
+func expectOneRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStateChanged
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+func TestTransitionWorktree(t *testing.T) {
+	store, err := NewStoreWithPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	defer store.Close()
+
+	repo := models.NewRepository("app", "/src/app", "main", 2, 0)
+	if err := store.CreateRepository(repo); err != nil {
+		t.Fatal(err)
+	}
+	wt := models.NewWorktree(repo.ID, "app-1", "/pool/app-1")
+	if err := store.CreateWorktree(wt); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	branch := "feature"
+	id := wt.ID.String()
+
+	if err := store.TransitionWorktree(id, models.WorktreeStatusIdle, models.WorktreeStatusInUse, &now, &branch); err != nil {
+		t.Fatalf("first claim: error = %v", err)
+	}
+	if err := store.TransitionWorktree(id, models.WorktreeStatusIdle, models.WorktreeStatusInUse, &now, &branch); !errors.Is(err, ErrStateChanged) {
+		t.Fatalf("second claim: error = %v, want ErrStateChanged", err)
+	}
+
+	// A failing transaction leaves the worktree as it was
+	rollback := errors.New("rollback")
+	err = store.WithTx(func(tx *Store) error {
+		if err := tx.TransitionWorktree(id, models.WorktreeStatusInUse, models.WorktreeStatusIdle, nil, nil); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, rollback)
+	}
+
+	got, err := store.GetWorktree(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.WorktreeStatusInUse || got.Branch == nil || *got.Branch != branch {
+		t.Errorf("after rollback: status %s, branch %v; want in-use on %s", got.Status, got.Branch, branch)
+	}
+}
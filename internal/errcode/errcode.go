@@ -0,0 +1,107 @@
+// Package errcode defines the stable error codes gitpool reports to clients,
+// in IPC responses and as CLI exit codes. Codes and exit codes are part of
+// the public interface: never change or reuse one, only add new ones and
+// document them in docs/errors.md.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Code string
+
+const (
+	Internal          Code = "internal"
+	InvalidArgument   Code = "invalid_argument"
+	DaemonUnavailable Code = "daemon_unavailable"
+	RepoNotFound      Code = "repo_not_found"
+	WorktreeNotFound  Code = "worktree_not_found"
+	RepoExists        Code = "repo_exists"
+	BranchInUse       Code = "branch_in_use"
+	PoolAtCapacity    Code = "pool_at_capacity"
+	WorktreeInUse     Code = "worktree_in_use"
+	WorktreeCorrupt   Code = "worktree_corrupt"
+	Conflict          Code = "conflict"
+	ProtocolMismatch  Code = "protocol_mismatch"
+	ShuttingDown      Code = "shutting_down"
+	Unauthorized      Code = "unauthorized"
+	PermissionDenied  Code = "permission_denied"
+	QuotaExceeded     Code = "quota_exceeded"
+)
+
+var exitCodes = map[Code]int{
+	Internal:          1,
+	InvalidArgument:   2,
+	DaemonUnavailable: 3,
+	RepoNotFound:      4,
+	WorktreeNotFound:  5,
+	RepoExists:        6,
+	BranchInUse:       7,
+	PoolAtCapacity:    8,
+	WorktreeInUse:     9,
+	WorktreeCorrupt:   10,
+	Conflict:          11,
+	ProtocolMismatch:  12,
+	ShuttingDown:      13,
+	Unauthorized:      14,
+	PermissionDenied:  15,
+	QuotaExceeded:     16,
+}
+
+// ExitCode returns the CLI exit code for a code; unknown codes, e.g. from a
+// newer daemon, exit like internal errors
+func ExitCode(code Code) int {
+	if exit, ok := exitCodes[code]; ok {
+		return exit
+	}
+	return exitCodes[Internal]
+}
+
+// Error is an error with a code. Err, if set, is the underlying cause.
+type Error struct {
+	Code Code
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// New returns an error with a code and a formatted message
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns an error with a code whose message is the formatted message
+// followed by err's
+func Wrap(code Code, err error, format string, args ...any) *Error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Coder is implemented by errors that carry a code
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Of returns the code of the first error in err's chain that has one, or
+// Internal
+func Of(err error) Code {
+	var coder Coder
+	if errors.As(err, &coder) && coder.ErrorCode() != "" {
+		return coder.ErrorCode()
+	}
+	return Internal
+}
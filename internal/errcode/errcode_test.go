@@ -0,0 +1,43 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"coded", New(BranchInUse, "branch '%s' is in use", "main"), BranchInUse},
+		{"wrapped", fmt.Errorf("claim failed: %w", New(PoolAtCapacity, "full")), PoolAtCapacity},
+		{"outermost code wins", Wrap(Conflict, New(RepoNotFound, "gone"), "update failed"), Conflict},
+		{"plain", errors.New("boom"), Internal},
+		{"empty code", New("", "from an old daemon"), Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Of(tt.err); got != tt.want {
+				t.Errorf("Of() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodesAreDistinct(t *testing.T) {
+	seen := make(map[int]Code)
+	for code, exit := range exitCodes {
+		if other, ok := seen[exit]; ok {
+			t.Errorf("%s and %s share exit code %d", code, other, exit)
+		}
+		seen[exit] = code
+	}
+
+	if got := ExitCode("added_by_a_newer_daemon"); got != ExitCode(Internal) {
+		t.Errorf("ExitCode(unknown) = %d, want %d", got, ExitCode(Internal))
+	}
+}
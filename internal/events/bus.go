@@ -0,0 +1,159 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventWorktreeCreated   EventType = "worktree.created"
+	EventWorktreeClaimed   EventType = "worktree.claimed"
+	EventWorktreeReleased  EventType = "worktree.released"
+	EventWorktreeCorrupted EventType = "worktree.corrupted"
+	EventWorktreeDeleted   EventType = "worktree.deleted"
+	EventRepoTracked       EventType = "repo.tracked"
+	EventRepoUntracked     EventType = "repo.untracked"
+	EventRepoUpdated       EventType = "repo.updated"
+	EventRefreshStarted    EventType = "refresh.started"
+	EventRefreshFinished   EventType = "refresh.finished"
+)
+
+// Event describes a single change to the pool. Events are serialized as one
+// JSON object per line on subscribed IPC connections.
+type Event struct {
+	Type       EventType              `json:"type"`
+	Time       time.Time              `json:"time"`
+	Repo       string                 `json:"repo,omitempty"`
+	WorktreeID string                 `json:"worktree_id,omitempty"`
+	Branch     string                 `json:"branch,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+const (
+	// historySize is the number of recent events replayed to new subscribers
+	historySize = 256
+	// subscriberBuffer is how many events may queue for a subscriber before
+	// it is considered too slow and dropped
+	subscriberBuffer = 64
+)
+
+// Bus fans out pool events to subscribers and keeps a short history of
+// recent events. A nil *Bus is valid and discards everything published to it.
+type Bus struct {
+	mu      sync.Mutex
+	subs    map[int]*subscriber
+	nextID  int
+	history []Event
+}
+
+type subscriber struct {
+	repo  string
+	allow func(Event) bool
+	ch    chan Event
+}
+
+// Subscription is a registered listener on a Bus. Backlog holds the recent
+// events that matched at the time of subscribing; C delivers new events and
+// is nil for non-following subscriptions. C is closed when the subscription
+// is closed or the subscriber falls too far behind.
+type Subscription struct {
+	Backlog []Event
+	C       <-chan Event
+	close   func()
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]*subscriber),
+	}
+}
+
+// Publish records an event and delivers it to all matching subscribers
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for id, sub := range b.subs {
+		if !sub.matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Never block the pool on a slow reader - drop the subscriber instead
+			close(sub.ch)
+			delete(b.subs, id)
+		}
+	}
+}
+
+// Subscribe returns the recent events for repo (all repos if empty) and, if
+// follow is set, a channel of subsequent events. If allow is set, only
+// events it accepts are delivered; it is called with the bus locked.
+func (b *Bus) Subscribe(repo string, follow bool, allow func(Event) bool) *Subscription {
+	if b == nil {
+		return &Subscription{close: func() {}}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{repo: repo, allow: allow}
+	var backlog []Event
+	for _, e := range b.history {
+		if sub.matches(e) {
+			backlog = append(backlog, e)
+		}
+	}
+
+	if !follow {
+		return &Subscription{Backlog: backlog, close: func() {}}
+	}
+
+	sub.ch = make(chan Event, subscriberBuffer)
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+
+	var once sync.Once
+	return &Subscription{
+		Backlog: backlog,
+		C:       sub.ch,
+		close: func() {
+			once.Do(func() {
+				b.mu.Lock()
+				defer b.mu.Unlock()
+				if _, ok := b.subs[id]; ok {
+					close(sub.ch)
+					delete(b.subs, id)
+				}
+			})
+		},
+	}
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.close()
+}
+
+func (s *subscriber) matches(e Event) bool {
+	if s.repo != "" && s.repo != e.Repo {
+		return false
+	}
+	return s.allow == nil || s.allow(e)
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBusReplaysHistory(t *testing.T) {
+	b := NewBus()
+	for i := 0; i < historySize+10; i++ {
+		b.Publish(Event{Type: EventWorktreeCreated, Repo: "app", WorktreeID: fmt.Sprint(i)})
+	}
+
+	sub := b.Subscribe("", false, nil)
+	defer sub.Close()
+	if sub.C != nil {
+		t.Error("Subscribe() without follow returned a channel")
+	}
+
+	// Only the newest historySize events are kept, oldest first
+	if len(sub.Backlog) != historySize {
+		t.Fatalf("Backlog has %d events, want %d", len(sub.Backlog), historySize)
+	}
+	if first := sub.Backlog[0].WorktreeID; first != "10" {
+		t.Errorf("oldest replayed event = %s, want 10", first)
+	}
+	if last := sub.Backlog[historySize-1].WorktreeID; last != fmt.Sprint(historySize+9) {
+		t.Errorf("newest replayed event = %s, want %d", last, historySize+9)
+	}
+	if sub.Backlog[0].Time.IsZero() {
+		t.Error("Publish() didn't set the event time")
+	}
+}
+
+func TestBusFiltersByRepo(t *testing.T) {
+	b := NewBus()
+	b.Publish(Event{Type: EventWorktreeCreated, Repo: "app"})
+	b.Publish(Event{Type: EventWorktreeCreated, Repo: "web"})
+
+	sub := b.Subscribe("app", true, nil)
+	defer sub.Close()
+	if len(sub.Backlog) != 1 || sub.Backlog[0].Repo != "app" {
+		t.Errorf("Backlog = %+v, want the one event of app", sub.Backlog)
+	}
+
+	b.Publish(Event{Type: EventWorktreeClaimed, Repo: "web"})
+	b.Publish(Event{Type: EventWorktreeClaimed, Repo: "app"})
+	if e := <-sub.C; e.Repo != "app" || e.Type != EventWorktreeClaimed {
+		t.Errorf("delivered event = %+v, want the claim in app", e)
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("delivered event of another repository: %+v", e)
+	default:
+	}
+}
+
+func TestBusFiltersByAllow(t *testing.T) {
+	b := NewBus()
+	b.Publish(Event{Type: EventWorktreeCreated, Repo: "app"})
+	b.Publish(Event{Type: EventWorktreeCreated, Repo: "secret"})
+
+	sub := b.Subscribe("", true, func(e Event) bool { return e.Repo != "secret" })
+	defer sub.Close()
+	if len(sub.Backlog) != 1 || sub.Backlog[0].Repo != "app" {
+		t.Errorf("Backlog = %+v, want only the allowed event", sub.Backlog)
+	}
+
+	b.Publish(Event{Type: EventWorktreeClaimed, Repo: "secret"})
+	b.Publish(Event{Type: EventWorktreeClaimed, Repo: "app"})
+	if e := <-sub.C; e.Repo != "app" {
+		t.Errorf("delivered event = %+v, want the one of app", e)
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	b := NewBus()
+	slow := b.Subscribe("", true, nil)
+	defer slow.Close()
+	fast := b.Subscribe("", true, nil)
+	defer fast.Close()
+
+	// The buffer holds subscriberBuffer events; one more drops the
+	// subscriber instead of blocking the publisher
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish(Event{Type: EventWorktreeCreated, Repo: "app", WorktreeID: fmt.Sprint(i)})
+		if i < subscriberBuffer {
+			<-fast.C
+		}
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("slow subscriber received %d events before being dropped, want %d", received, subscriberBuffer)
+	}
+
+	// The subscriber that kept up still gets events
+	if e, ok := <-fast.C; !ok || e.WorktreeID != fmt.Sprint(subscriberBuffer) {
+		t.Errorf("fast subscriber got %+v, %v, want the last event", e, ok)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) != 1 {
+		t.Errorf("bus has %d subscribers, want 1", len(b.subs))
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	b := NewBus()
+	sub := b.Subscribe("", true, nil)
+	sub.Close()
+	sub.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Error("channel still open after Close()")
+	}
+	// Publishing after Close doesn't deliver to, or panic on, the closed channel
+	b.Publish(Event{Type: EventWorktreeCreated, Repo: "app"})
+
+	var nilBus *Bus
+	nilBus.Publish(Event{Type: EventWorktreeCreated})
+	nilBus.Subscribe("", true, nil).Close()
+}
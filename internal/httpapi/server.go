@@ -0,0 +1,454 @@
+// Package httpapi serves the daemon's operations as a REST API over TCP, for
+// clients that can't reach the unix socket, such as CI runners in
+// containers. Every request but the OpenAPI description needs the bearer
+// token from the daemon's token file. Shutdown and restart are only offered
+// on the socket.
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"github.com/albertywu/gitpool/internal/logging"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// maxBodySize bounds request bodies, which are all small JSON objects
+const maxBodySize = 1 << 20
+
+// defaultStatsWindow is the stats period when a request doesn't give one,
+// matching 'gp stats'
+const defaultStatsWindow = 7 * 24 * time.Hour
+
+type Server struct {
+	handler ipc.Handler
+	token   string
+	routes  []route
+	log     *slog.Logger
+
+	listener net.Listener
+	srv      *http.Server
+	// streams is cancelled on shutdown to end event streams, which would
+	// otherwise keep Shutdown waiting forever
+	streams context.Context
+	cancel  context.CancelFunc
+}
+
+// NewServer listens on addr and authenticates requests with token. Call
+// Serve to start handling them.
+func NewServer(addr, token string, handler ipc.Handler) (*Server, error) {
+	if token == "" {
+		return nil, fmt.Errorf("HTTP API token is empty")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := newServer(token, handler)
+	s.listener = listener
+	s.srv = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return s.streams },
+	}
+	return s, nil
+}
+
+func newServer(token string, handler ipc.Handler) *Server {
+	s := &Server{
+		handler: handler,
+		token:   token,
+		log:     logging.Component("http"),
+	}
+	s.streams, s.cancel = context.WithCancel(context.Background())
+	s.routes = s.buildRoutes()
+	return s
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Serve handles requests until Shutdown is called
+func (s *Server) Serve() error {
+	if err := s.srv.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("HTTP API server failed: %w", err)
+	}
+	return nil
+}
+
+// Shutdown stops accepting requests, ends event streams and waits for
+// in-flight requests to finish. It is safe to call more than once.
+func (s *Server) Shutdown() error {
+	s.cancel()
+	return s.srv.Shutdown(context.Background())
+}
+
+// ServeHTTP authenticates and dispatches a request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	s.serve(rec, r)
+
+	log := s.log.With("method", r.Method, "path", r.URL.Path, "status", rec.status,
+		"remote", r.RemoteAddr, "duration_ms", time.Since(start).Milliseconds())
+	if rec.status < 400 {
+		log.Debug("Handled request")
+	} else {
+		log.Info("Request failed", "code", rec.code)
+	}
+}
+
+func (s *Server) serve(w *statusRecorder, r *http.Request) {
+	if r.URL.Path == "/v1/openapi.yaml" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gitpool"`)
+		writeResponse(w, ipc.ErrorResponse(errcode.New(errcode.Unauthorized, "missing or invalid bearer token")))
+		return
+	}
+
+	rt, params, allowed := s.match(r.Method, r.URL.Path)
+	if rt == nil {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, errcode.InvalidArgument,
+				fmt.Sprintf("method %s not allowed for %s", r.Method, r.URL.Path))
+			return
+		}
+		writeError(w, http.StatusNotFound, errcode.InvalidArgument, fmt.Sprintf("no such endpoint %s", r.URL.Path))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	rt.serve(w, r, params)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// route is an endpoint; {name} segments in pattern match any one segment
+type route struct {
+	method  string
+	pattern []string
+	serve   func(w *statusRecorder, r *http.Request, params map[string]string)
+}
+
+// match finds the route for a request. If only the method doesn't match,
+// it returns the methods the path allows instead.
+func (s *Server) match(method, path string) (*route, map[string]string, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var allowed []string
+
+	for i := range s.routes {
+		rt := &s.routes[i]
+		params, ok := matchPattern(rt.pattern, segments)
+		if !ok {
+			continue
+		}
+		if rt.method != method {
+			allowed = append(allowed, rt.method)
+			continue
+		}
+		return rt, params, nil
+	}
+	return nil, nil, allowed
+}
+
+func matchPattern(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[p[1:len(p)-1]] = segments[i]
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) buildRoutes() []route {
+	// call adapts a handler returning an ipc.Response to a route
+	call := func(fn func(r *http.Request, params map[string]string) ipc.Response) func(*statusRecorder, *http.Request, map[string]string) {
+		return func(w *statusRecorder, r *http.Request, params map[string]string) {
+			writeResponse(w, fn(r, params))
+		}
+	}
+	add := func(routes []route, method, pattern string, fn func(*statusRecorder, *http.Request, map[string]string)) []route {
+		return append(routes, route{method: method, pattern: strings.Split(strings.Trim(pattern, "/"), "/"), serve: fn})
+	}
+
+	var routes []route
+	routes = add(routes, http.MethodGet, "/v1/status", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandleDaemonStatus(ipc.DaemonStatusRequest{})
+	}))
+
+	routes = add(routes, http.MethodGet, "/v1/repos", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandleRepoList()
+	}))
+	routes = add(routes, http.MethodPost, "/v1/repos", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		var req ipc.RepoAddRequest
+		if err := decodeBody(r, &req); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return s.handler.HandleRepoAdd(req)
+	}))
+	routes = add(routes, http.MethodPatch, "/v1/repos/{repo}", call(func(r *http.Request, p map[string]string) ipc.Response {
+		var req ipc.RepoUpdateRequest
+		if err := decodeBody(r, &req); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		req.Name = p["repo"]
+		return s.handler.HandleRepoUpdate(req)
+	}))
+	routes = add(routes, http.MethodDelete, "/v1/repos/{repo}", call(func(r *http.Request, p map[string]string) ipc.Response {
+		return s.handler.HandleRepoRemove(p["repo"])
+	}))
+	routes = add(routes, http.MethodPost, "/v1/repos/{repo}/claim", call(func(r *http.Request, p map[string]string) ipc.Response {
+		var req ipc.ClaimRequest
+		if err := decodeBody(r, &req); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		req.RepoName = p["repo"]
+		return s.handler.HandleClaim(req)
+	}))
+	routes = add(routes, http.MethodPost, "/v1/repos/{repo}/refresh", call(func(r *http.Request, p map[string]string) ipc.Response {
+		return s.handler.HandleRefresh(ipc.RefreshRequest{RepoName: p["repo"]})
+	}))
+
+	routes = add(routes, http.MethodGet, "/v1/worktrees", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandleWorktreeList(ipc.WorktreeListRequest{})
+	}))
+	routes = add(routes, http.MethodGet, "/v1/worktrees/{id}", call(func(r *http.Request, p map[string]string) ipc.Response {
+		return s.handler.HandleShow(ipc.ShowRequest{WorktreeID: p["id"]})
+	}))
+	routes = add(routes, http.MethodPost, "/v1/worktrees/{id}/release", call(func(r *http.Request, p map[string]string) ipc.Response {
+		return s.handler.HandleRelease(ipc.ReleaseRequest{WorktreeID: p["id"]})
+	}))
+
+	routes = add(routes, http.MethodGet, "/v1/pool", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandlePoolStatus(ipc.PoolStatusRequest{RepoName: r.URL.Query().Get("repo")})
+	}))
+	routes = add(routes, http.MethodGet, "/v1/history", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		q := r.URL.Query()
+		req := ipc.HistoryRequest{RepoName: q.Get("repo"), Branch: q.Get("branch")}
+		if v := q.Get("since"); v != "" {
+			since, err := parseTime("since", v)
+			if err != nil {
+				return ipc.ErrorResponse(err)
+			}
+			req.Since = &since
+		}
+		if v := q.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 0 {
+				return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "limit '%s' is not a non-negative integer", v))
+			}
+			req.Limit = limit
+		}
+		if v := q.Get("rejected"); v != "" {
+			rejected, err := strconv.ParseBool(v)
+			if err != nil {
+				return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "rejected '%s' is not a boolean", v))
+			}
+			req.Rejected = rejected
+		}
+		return s.handler.HandleHistory(req)
+	}))
+	routes = add(routes, http.MethodGet, "/v1/stats", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		q := r.URL.Query()
+		req := ipc.StatsRequest{RepoName: q.Get("repo"), Since: time.Now().Add(-defaultStatsWindow)}
+		if v := q.Get("since"); v != "" {
+			since, err := parseTime("since", v)
+			if err != nil {
+				return ipc.ErrorResponse(err)
+			}
+			req.Since = since
+		}
+		return s.handler.HandleStats(req)
+	}))
+	routes = add(routes, http.MethodGet, "/v1/events", s.streamEvents)
+
+	routes = add(routes, http.MethodGet, "/v1/log-level", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandleLogLevel(ipc.LogLevelRequest{})
+	}))
+	routes = add(routes, http.MethodPut, "/v1/log-level", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		var req ipc.LogLevelRequest
+		if err := decodeBody(r, &req); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		if req.Level == "" {
+			return ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "level is required"))
+		}
+		return s.handler.HandleLogLevel(req)
+	}))
+	routes = add(routes, http.MethodPost, "/v1/reload", call(func(r *http.Request, _ map[string]string) ipc.Response {
+		return s.handler.HandleReload()
+	}))
+
+	return routes
+}
+
+// streamEvents writes one JSON event per line, the events the daemon still
+// remembers first, until the subscription ends, the client disconnects or
+// the server shuts down. Without ?follow=true only remembered events are
+// written.
+func (s *Server) streamEvents(w *statusRecorder, r *http.Request, _ map[string]string) {
+	q := r.URL.Query()
+	req := ipc.SubscribeRequest{RepoName: q.Get("repo")}
+	if v := q.Get("follow"); v != "" {
+		follow, err := strconv.ParseBool(v)
+		if err != nil {
+			writeResponse(w, ipc.ErrorResponse(errcode.New(errcode.InvalidArgument, "follow '%s' is not a boolean", v)))
+			return
+		}
+		req.Follow = follow
+	}
+
+	sub := s.handler.HandleSubscribe(req)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+
+	for _, e := range sub.Backlog {
+		if err := encoder.Encode(e); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	if sub.C == nil {
+		return
+	}
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(e); err != nil {
+				return
+			}
+			w.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// decodeBody reads a JSON request body into v. An empty body leaves v as is.
+func decodeBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return errcode.Wrap(errcode.InvalidArgument, err, "invalid request body")
+}
+
+func parseTime(name, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errcode.New(errcode.InvalidArgument, "%s '%s' is not an RFC 3339 timestamp", name, value)
+	}
+	return t, nil
+}
+
+// httpStatus maps an error code to the HTTP status of a failed response
+func httpStatus(code errcode.Code) int {
+	switch code {
+	case errcode.InvalidArgument:
+		return http.StatusBadRequest
+	case errcode.Unauthorized:
+		return http.StatusUnauthorized
+	case errcode.PermissionDenied:
+		return http.StatusForbidden
+	case errcode.QuotaExceeded:
+		return http.StatusTooManyRequests
+	case errcode.RepoNotFound, errcode.WorktreeNotFound:
+		return http.StatusNotFound
+	case errcode.RepoExists, errcode.BranchInUse, errcode.WorktreeInUse, errcode.Conflict:
+		return http.StatusConflict
+	case errcode.PoolAtCapacity, errcode.ShuttingDown, errcode.DaemonUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeResponse writes resp in the same envelope as socket responses, with
+// an HTTP status matching its error code
+func writeResponse(w *statusRecorder, resp ipc.Response) {
+	status := http.StatusOK
+	if !resp.Success {
+		if resp.Code == "" {
+			resp.Code = errcode.Internal
+		}
+		status = httpStatus(resp.Code)
+		w.code = resp.Code
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w *statusRecorder, status int, code errcode.Code, msg string) {
+	w.code = code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ipc.Response{Success: false, Error: msg, Code: code})
+}
+
+// statusRecorder remembers the status and error code of a response for the
+// request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	code   errcode.Code
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
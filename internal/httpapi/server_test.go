@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/ipc"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeHandler implements the handlers the tests call; any other one panics
+type fakeHandler struct {
+	ipc.Handler
+	claims   []ipc.ClaimRequest
+	released []string
+}
+
+func (h *fakeHandler) HandleClaim(req ipc.ClaimRequest) ipc.Response {
+	h.claims = append(h.claims, req)
+	if req.Branch == "taken" {
+		return ipc.ErrorResponse(errcode.New(errcode.BranchInUse, "branch '%s' is already in use", req.Branch))
+	}
+	return ipc.Response{Success: true, Data: ipc.ClaimResponse{WorktreeID: "my-app-1", Path: "/tmp/my-app-1"}}
+}
+
+func (h *fakeHandler) HandleRelease(req ipc.ReleaseRequest) ipc.Response {
+	h.released = append(h.released, req.WorktreeID)
+	return ipc.Response{Success: true}
+}
+
+func (h *fakeHandler) HandleRepoList() ipc.Response {
+	return ipc.Response{Success: true, Data: []string{}}
+}
+
+func TestServer(t *testing.T) {
+	handler := &fakeHandler{}
+	ts := httptest.NewServer(newServer("secret", handler))
+	defer ts.Close()
+
+	do := func(method, path, token, body string) (*http.Response, ipc.Response) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out ipc.Response
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+		return resp, out
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantCode   errcode.Code
+	}{
+		{"no token", "GET", "/v1/repos", "", "", http.StatusUnauthorized, errcode.Unauthorized},
+		{"wrong token", "GET", "/v1/repos", "wrong", "", http.StatusUnauthorized, errcode.Unauthorized},
+		{"list", "GET", "/v1/repos", "secret", "", http.StatusOK, ""},
+		{"claim", "POST", "/v1/repos/my-app/claim", "secret", `{"branch":"feature","owner":"ci"}`, http.StatusOK, ""},
+		{"claim conflict", "POST", "/v1/repos/my-app/claim", "secret", `{"branch":"taken"}`, http.StatusConflict, errcode.BranchInUse},
+		{"bad body", "POST", "/v1/repos/my-app/claim", "secret", `{"branch":`, http.StatusBadRequest, errcode.InvalidArgument},
+		{"release", "POST", "/v1/worktrees/my-app-1/release", "secret", "", http.StatusOK, ""},
+		{"wrong method", "DELETE", "/v1/worktrees/my-app-1/release", "secret", "", http.StatusMethodNotAllowed, errcode.InvalidArgument},
+		{"unknown path", "GET", "/v1/nope", "secret", "", http.StatusNotFound, errcode.InvalidArgument},
+		{"bad since", "GET", "/v1/history?since=7d", "secret", "", http.StatusBadRequest, errcode.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, out := do(tt.method, tt.path, tt.token, tt.body)
+			if resp.StatusCode != tt.wantStatus || out.Code != tt.wantCode {
+				t.Errorf("%s %s = %d %q, want %d %q (error %q)", tt.method, tt.path,
+					resp.StatusCode, out.Code, tt.wantStatus, tt.wantCode, out.Error)
+			}
+			if out.Success != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("success = %v with status %d", out.Success, resp.StatusCode)
+			}
+		})
+	}
+
+	if len(handler.claims) != 2 || handler.claims[0] != (ipc.ClaimRequest{RepoName: "my-app", Branch: "feature", Owner: "ci"}) {
+		t.Errorf("claims = %+v, want repo from the path and branch and owner from the body", handler.claims)
+	}
+	if len(handler.released) != 1 || handler.released[0] != "my-app-1" {
+		t.Errorf("released = %v, want [my-app-1]", handler.released)
+	}
+}
+
+// TestOpenAPIDescribesRoutes keeps the embedded description in step with the
+// routes actually served
+func TestOpenAPIDescribesRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.yaml doesn't parse: %v", err)
+	}
+
+	s := newServer("secret", &fakeHandler{})
+	served := make(map[string]bool)
+	for _, rt := range s.routes {
+		path := "/" + strings.Join(rt.pattern, "/")
+		served[path+" "+strings.ToLower(rt.method)] = true
+		if _, ok := spec.Paths[path][strings.ToLower(rt.method)]; !ok {
+			t.Errorf("%s %s is served but not described", rt.method, path)
+		}
+	}
+
+	for path, ops := range spec.Paths {
+		if path == "/v1/openapi.yaml" {
+			continue
+		}
+		for method := range ops {
+			if method != "parameters" && !served[path+" "+method] {
+				t.Errorf("%s %s is described but not served", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadToken reads the bearer token from path, first writing a new random
+// token there if the file doesn't exist. created reports whether it did.
+func LoadToken(path string) (token string, created bool, err error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", false, fmt.Errorf("HTTP API token file %s is empty", path)
+		}
+		return token, false, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", false, fmt.Errorf("failed to read HTTP API token: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, fmt.Errorf("failed to generate HTTP API token: %w", err)
+	}
+	token = hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", false, fmt.Errorf("failed to create token directory: %w", err)
+	}
+	// O_EXCL so a concurrently written token is never overwritten
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to write HTTP API token: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		return "", false, fmt.Errorf("failed to write HTTP API token: %w", err)
+	}
+	return token, true, nil
+}
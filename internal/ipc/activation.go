@@ -0,0 +1,80 @@
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+// HandoffFDEnv names the file descriptor of the listening socket passed by a
+// restarting daemon to its replacement
+const HandoffFDEnv = "GITPOOL_INHERIT_FD"
+
+// SocketActivated reports whether this process was started by systemd with a
+// listening socket to serve
+func SocketActivated() bool {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return false
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	return err == nil && n > 0
+}
+
+// HandoffPending reports whether a restarting daemon passed this process its
+// listening socket. The old daemon still holds the instance lock until it has
+// drained.
+func HandoffPending() bool {
+	return os.Getenv(HandoffFDEnv) != ""
+}
+
+// handoffListener returns the socket passed by a restarting daemon, or nil if
+// there is none
+func handoffListener() (net.Listener, error) {
+	value := os.Getenv(HandoffFDEnv)
+	if value == "" {
+		return nil, nil
+	}
+	os.Unsetenv(HandoffFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < listenFDsStart {
+		return nil, fmt.Errorf("invalid %s value '%s'", HandoffFDEnv, value)
+	}
+	return fileListener(fd, "restarting daemon")
+}
+
+// activationListener returns the socket passed by systemd, or nil if the
+// process was not socket-activated. Only the first socket is used.
+func activationListener() (net.Listener, error) {
+	if !SocketActivated() {
+		return nil, nil
+	}
+
+	// Don't leak the activation environment into processes we spawn
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	return fileListener(listenFDsStart, "systemd")
+}
+
+func fileListener(fd int, from string) (net.Listener, error) {
+	file := os.NewFile(uintptr(fd), "inherited-socket")
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use socket passed by %s: %w", from, err)
+	}
+	if _, ok := listener.(*net.UnixListener); !ok {
+		listener.Close()
+		return nil, fmt.Errorf("socket passed by %s is not a unix socket", from)
+	}
+	return listener, nil
+}
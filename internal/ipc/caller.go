@@ -0,0 +1,97 @@
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// Caller identifies the process on the other end of a socket connection, as
+// reported by the kernel
+type Caller struct {
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+	PID int `json:"pid"`
+}
+
+// Authorizer is implemented by handlers that restrict requests by caller.
+// Authorize is called before every request except hello, with a nil caller
+// where the platform can't identify callers; a non-nil error is returned to
+// the client instead of handling the request.
+type Authorizer interface {
+	Authorize(caller *Caller, msgType MessageType) error
+}
+
+// SocketOptions controls who may connect to a socket the server creates.
+// Sockets passed in by systemd or a restarting daemon keep their permissions.
+type SocketOptions struct {
+	// Mode holds the socket's permission bits; zero means 0600, or 0660
+	// with a Group
+	Mode os.FileMode
+	// Group, a group name or ID, owns the socket when set
+	Group string
+}
+
+func (o SocketOptions) mode() os.FileMode {
+	switch {
+	case o.Mode != 0:
+		return o.Mode
+	case o.Group != "":
+		return 0660
+	default:
+		return 0600
+	}
+}
+
+// apply sets the permissions and group of the socket at path
+func (o SocketOptions) apply(path string) error {
+	if o.mode()&0077 != 0 && !peerCredSupported {
+		return fmt.Errorf("sharing the socket with other users needs peer credentials, which this platform doesn't support")
+	}
+
+	if o.Group != "" {
+		gid, err := LookupGroupID(o.Group)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+
+	if err := os.Chmod(path, o.mode()); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return nil
+}
+
+// LookupGroupID resolves a group name or numeric ID
+func LookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up group '%s': %w", group, err)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group '%s' has non-numeric ID '%s'", group, g.Gid)
+	}
+	return gid, nil
+}
+
+// callerOf identifies the peer of a connection, or returns nil if the
+// platform can't
+func callerOf(conn net.Conn) (*Caller, error) {
+	if !peerCredSupported {
+		return nil, nil
+	}
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("connection is not a unix socket")
+	}
+	return peerCred(unixConn)
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const peerCredSupported = true
+
+// peerCred reads the credentials of the peer process with SO_PEERCRED
+func peerCred(conn *net.UnixConn) (*Caller, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+	if credErr != nil {
+		return nil, fmt.Errorf("failed to read peer credentials: %w", credErr)
+	}
+
+	return &Caller{UID: int(cred.Uid), GID: int(cred.Gid), PID: int(cred.Pid)}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package ipc
+
+import (
+	"fmt"
+	"net"
+)
+
+// Peer credentials are only read on Linux. Elsewhere the socket must stay
+// private to the daemon's user, so every caller is that user.
+const peerCredSupported = false
+
+func peerCred(conn *net.UnixConn) (*Caller, error) {
+	return nil, fmt.Errorf("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,152 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/version"
+)
+
+// ProtocolVersion is the version of the messages exchanged over the socket.
+// Bump it whenever a peer that doesn't know a change would misbehave, e.g. a
+// new message type or a request field an older daemon would silently ignore.
+const ProtocolVersion = 1
+
+// unknownMessageType prefixes the error for message types the daemon doesn't
+// know. Daemons that predate protocol versions answer with exactly this, so
+// clients rely on it to detect them.
+const unknownMessageType = "unknown message type"
+
+// features lists the message types the daemon handles, reported by hello
+var features = []MessageType{
+	MessageTypeHello,
+	MessageTypeRepoAdd,
+	MessageTypeRepoList,
+	MessageTypeRepoUpdate,
+	MessageTypeRepoRemove,
+	MessageTypeClaim,
+	MessageTypeRelease,
+	MessageTypePoolStatus,
+	MessageTypeDaemonStatus,
+	MessageTypeWorktreeList,
+	MessageTypeRefresh,
+	MessageTypeShow,
+	MessageTypeSubscribe,
+	MessageTypeHistory,
+	MessageTypeStats,
+	MessageTypeShutdown,
+	MessageTypeRestart,
+	MessageTypeLogLevel,
+	MessageTypeReload,
+}
+
+type HelloRequest struct {
+	ClientVersion string `json:"client_version"`
+	Protocol      int    `json:"protocol"`
+}
+
+// HelloResponse describes the daemon to a client
+type HelloResponse struct {
+	Version  string   `json:"version"`
+	Protocol int      `json:"protocol"`
+	PID      int      `json:"pid"`
+	Features []string `json:"features"`
+}
+
+// ProtocolError is returned by the client when the daemon doesn't understand
+// a request because it is older than the client
+type ProtocolError struct {
+	// DaemonVersion and DaemonProtocol are empty and zero for daemons that
+	// predate protocol versions
+	DaemonVersion  string
+	DaemonProtocol int
+	Request        MessageType
+}
+
+func (e *ProtocolError) ErrorCode() errcode.Code {
+	return errcode.ProtocolMismatch
+}
+
+func (e *ProtocolError) Error() string {
+	if e.DaemonProtocol == 0 {
+		return fmt.Sprintf("the running daemon is older than this gp (%s) and doesn't support '%s'; run 'gp restart' to upgrade it",
+			version.Version, e.Request)
+	}
+	return fmt.Sprintf("the running daemon (%s, protocol %d) doesn't support '%s' from this gp (%s, protocol %d); run 'gp restart' to upgrade it",
+		e.DaemonVersion, e.DaemonProtocol, e.Request, version.Version, ProtocolVersion)
+}
+
+func (s *Server) hello(req HelloRequest) Response {
+	s.log.Debug("Client connected", "op", string(MessageTypeHello),
+		"client_version", req.ClientVersion, "client_protocol", req.Protocol)
+
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = string(f)
+	}
+
+	return Response{Success: true, Data: HelloResponse{
+		Version:  version.Version,
+		Protocol: ProtocolVersion,
+		PID:      os.Getpid(),
+		Features: names,
+	}}
+}
+
+// checkProtocol rejects requests from clients newer than the daemon. Hello
+// is always answered, so any client can find out what it is talking to.
+func checkProtocol(msg Message) *Response {
+	if msg.Version <= ProtocolVersion || msg.Type == MessageTypeHello {
+		return nil
+	}
+	resp := ErrorResponse(errcode.New(errcode.ProtocolMismatch,
+		"this gp speaks protocol %d but the running daemon (%s) only supports up to %d; run 'gp restart' to upgrade it",
+		msg.Version, version.Version, ProtocolVersion))
+	return &resp
+}
+
+// Hello asks the daemon for its version and supported features
+func (c *Client) Hello() (*HelloResponse, error) {
+	return c.hello(context.Background())
+}
+
+func (c *Client) hello(ctx context.Context) (*HelloResponse, error) {
+	data, _ := json.Marshal(HelloRequest{ClientVersion: version.Version, Protocol: ProtocolVersion})
+	resp, err := c.roundTrip(ctx, Message{Type: MessageTypeHello, Version: ProtocolVersion, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		if strings.HasPrefix(resp.Error, unknownMessageType) {
+			return nil, &ProtocolError{Request: MessageTypeHello}
+		}
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+
+	data, _ = json.Marshal(resp.Data)
+	var hello HelloResponse
+	if err := json.Unmarshal(data, &hello); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &hello, nil
+}
+
+// unsupported explains an "unknown message type" answer by asking the daemon
+// what it is
+func (c *Client) unsupported(ctx context.Context, msgType MessageType) error {
+	hello, err := c.hello(ctx)
+	if err != nil {
+		var protoErr *ProtocolError
+		if errors.As(err, &protoErr) {
+			protoErr.Request = msgType
+			return protoErr
+		}
+		return &ProtocolError{Request: msgType}
+	}
+	return &ProtocolError{DaemonVersion: hello.Version, DaemonProtocol: hello.Protocol, Request: msgType}
+}
@@ -1,17 +1,31 @@
 package ipc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/logging"
 )
 
 type MessageType string
 
 const (
+	MessageTypeHello        MessageType = "hello"
 	MessageTypeRepoAdd      MessageType = "repo_add"
 	MessageTypeRepoList     MessageType = "repo_list"
+	MessageTypeRepoUpdate   MessageType = "repo_update"
 	MessageTypeRepoRemove   MessageType = "repo_remove"
 	MessageTypeClaim        MessageType = "claim"
 	MessageTypeRelease      MessageType = "release"
@@ -20,19 +34,36 @@ const (
 	MessageTypeWorktreeList MessageType = "worktree_list"
 	MessageTypeRefresh      MessageType = "refresh"
 	MessageTypeShow         MessageType = "show"
+	MessageTypeSubscribe    MessageType = "subscribe"
+	MessageTypeHistory      MessageType = "history"
+	MessageTypeStats        MessageType = "stats"
+	MessageTypeShutdown     MessageType = "shutdown"
+	MessageTypeRestart      MessageType = "restart"
+	MessageTypeLogLevel     MessageType = "log_level"
+	MessageTypeReload       MessageType = "reload"
 	MessageTypeResponse     MessageType = "response"
 	MessageTypeError        MessageType = "error"
 )
 
 type Message struct {
-	Type MessageType     `json:"type"`
-	Data json.RawMessage `json:"data,omitempty"`
+	Type MessageType `json:"type"`
+	// Version is the sender's ProtocolVersion; zero for clients that predate
+	// protocol versions
+	Version int             `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Code classifies a failure; see package errcode
+	Code errcode.Code `json:"code,omitempty"`
+}
+
+// ErrorResponse returns a failed response carrying err's message and code
+func ErrorResponse(err error) Response {
+	return Response{Success: false, Error: err.Error(), Code: errcode.Of(err)}
 }
 
 type RepoAddRequest struct {
@@ -42,9 +73,23 @@ type RepoAddRequest struct {
 	BaseBranch   string `json:"base_branch"`
 }
 
+// RepoUpdateRequest changes settings of a tracked repository; nil fields are
+// left unchanged
+type RepoUpdateRequest struct {
+	Name         string  `json:"name"`
+	MaxWorktrees *int    `json:"max_worktrees,omitempty"`
+	BaseBranch   *string `json:"base_branch,omitempty"`
+	// FetchInterval is in minutes; zero turns scheduled fetches off
+	FetchInterval *int `json:"fetch_interval_minutes,omitempty"`
+}
+
 type ClaimRequest struct {
 	RepoName string `json:"repo_name"`
 	Branch   string `json:"branch"`
+	Owner    string `json:"owner,omitempty"`
+	// Caller is set by the server from the socket connection; nil for
+	// callers with full access, e.g. on the HTTP API
+	Caller *Caller `json:"-"`
 }
 
 type ClaimResponse struct {
@@ -53,77 +98,329 @@ type ClaimResponse struct {
 }
 
 type ReleaseRequest struct {
-	WorktreeID string `json:"worktree_id"`
+	WorktreeID string  `json:"worktree_id"`
+	Caller     *Caller `json:"-"`
 }
 
 type PoolStatusRequest struct {
-	RepoName string `json:"repo_name,omitempty"`
+	RepoName string  `json:"repo_name,omitempty"`
+	Caller   *Caller `json:"-"`
+}
+
+// WorktreeListRequest lists the worktrees the caller may see; it has no
+// parameters
+type WorktreeListRequest struct {
+	Caller *Caller `json:"-"`
+}
+
+// DaemonStatusRequest describes the daemon, with the quota usage the caller
+// may see; it has no parameters
+type DaemonStatusRequest struct {
+	Caller *Caller `json:"-"`
 }
 
 type RefreshRequest struct {
-	RepoName string `json:"repo_name"`
+	RepoName string  `json:"repo_name"`
+	Caller   *Caller `json:"-"`
 }
 
 type ShowRequest struct {
-	WorktreeID string `json:"worktree_id"`
+	WorktreeID string  `json:"worktree_id"`
+	Caller     *Caller `json:"-"`
+}
+
+type HistoryRequest struct {
+	RepoName string     `json:"repo_name,omitempty"`
+	Branch   string     `json:"branch,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+	Limit    int        `json:"limit,omitempty"`
+	// Rejected includes claims that failed with pool_at_capacity
+	Rejected bool    `json:"rejected,omitempty"`
+	Caller   *Caller `json:"-"`
 }
 
+type StatsRequest struct {
+	RepoName string    `json:"repo_name,omitempty"`
+	Since    time.Time `json:"since"`
+	Caller   *Caller   `json:"-"`
+}
+
+// LogLevelRequest changes the daemon's log level; an empty level only
+// reports the current one
+type LogLevelRequest struct {
+	Level string `json:"level,omitempty"`
+}
+
+// ReloadResponse lists the config changes a reload applied and the ones it
+// refused, such as settings that only take effect on restart
+type ReloadResponse struct {
+	Applied  []string          `json:"applied"`
+	Rejected []RejectedSetting `json:"rejected"`
+}
+
+type RejectedSetting struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+type SubscribeRequest struct {
+	RepoName string  `json:"repo_name,omitempty"`
+	Follow   bool    `json:"follow,omitempty"`
+	Caller   *Caller `json:"-"`
+}
+
+// RestartRequest asks the daemon to hand its socket to a new process running
+// Executable, typically the upgraded gp binary of the requesting client. The
+// daemon only runs it for callers who are the daemon's own user or root.
+type RestartRequest struct {
+	Executable string  `json:"executable,omitempty"`
+	Caller     *Caller `json:"-"`
+}
+
+type RestartResponse struct {
+	OldPID int `json:"old_pid"`
+	NewPID int `json:"new_pid"`
+}
+
+type ShutdownResponse struct {
+	PID            int      `json:"pid"`
+	InUseWorktrees []string `json:"in_use_worktrees"`
+}
+
+// closeWaitTimeout bounds how long Close waits for open connections to finish
+// writing their responses
+const closeWaitTimeout = 5 * time.Second
+
+// requestReadTimeout bounds how long a new connection may take to send its
+// request
+const requestReadTimeout = 10 * time.Second
+
 type Server struct {
 	socketPath string
 	listener   net.Listener
 	handler    Handler
+	inherited  bool
+	log        *slog.Logger
+
+	mu       sync.Mutex
+	idle     *sync.Cond
+	draining bool
+	handoff  bool // the socket lives on in another process
+	pending  int  // accepted connections whose request hasn't been read yet
+	active   int  // requests being handled, not counting shutdown or streams
+	open     int  // all open connections
+	streams  map[net.Conn]struct{}
 }
 
 type Handler interface {
 	HandleRepoAdd(req RepoAddRequest) Response
 	HandleRepoList() Response
 	HandleRepoRemove(name string) Response
+	HandleRepoUpdate(req RepoUpdateRequest) Response
 	HandleClaim(req ClaimRequest) Response
 	HandleRelease(req ReleaseRequest) Response
 	HandlePoolStatus(req PoolStatusRequest) Response
-	HandleDaemonStatus() Response
-	HandleWorktreeList() Response
+	HandleDaemonStatus(req DaemonStatusRequest) Response
+	HandleWorktreeList(req WorktreeListRequest) Response
 	HandleRefresh(req RefreshRequest) Response
 	HandleShow(req ShowRequest) Response
+	HandleSubscribe(req SubscribeRequest) *events.Subscription
+	HandleHistory(req HistoryRequest) Response
+	HandleStats(req StatsRequest) Response
+	HandleLogLevel(req LogLevelRequest) Response
+	HandleReload() Response
+	// HandleShutdown is called without counting as an in-flight request, so
+	// it may call Drain to wait for all other requests to finish
+	HandleShutdown() Response
+	// HandleRestart is called like HandleShutdown
+	HandleRestart(req RestartRequest) Response
 }
 
-func NewServer(socketPath string, handler Handler) (*Server, error) {
-	// Remove existing socket if exists
-	os.Remove(socketPath)
-
-	listener, err := net.Listen("unix", socketPath)
+// NewServer listens on socketPath. A handler implementing Authorizer decides
+// which callers may send which requests.
+func NewServer(socketPath string, handler Handler, opts SocketOptions) (*Server, error) {
+	// A socket passed by systemd is already bound and owned by systemd, so it
+	// must not be removed or re-created
+	listener, err := activationListener()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create unix socket: %w", err)
+		return nil, err
 	}
+	inherited := listener != nil
 
-	// Set socket permissions
-	if err := os.Chmod(socketPath, 0600); err != nil {
-		listener.Close()
-		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	// A socket handed over by a restarting daemon is ours from now on
+	if listener == nil {
+		if listener, err = handoffListener(); err != nil {
+			return nil, err
+		}
 	}
 
-	return &Server{
+	if listener == nil {
+		// Remove existing socket if exists
+		os.Remove(socketPath)
+
+		listener, err = net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create unix socket: %w", err)
+		}
+
+		if err := opts.apply(socketPath); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	s := &Server{
 		socketPath: socketPath,
 		listener:   listener,
+		inherited:  inherited,
+		log:        logging.Component("ipc"),
 		handler:    handler,
-	}, nil
+		streams:    make(map[net.Conn]struct{}),
+	}
+	s.idle = sync.NewCond(&s.mu)
+
+	return s, nil
 }
 
+// Inherited reports whether the server uses a socket it did not create, which
+// it must leave in place on shutdown
+func (s *Server) Inherited() bool {
+	return s.inherited
+}
+
+// Serve accepts connections until the server is drained or closed
 func (s *Server) Serve() error {
 	defer s.listener.Close()
 
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {
+			if s.isDraining() && errors.Is(err, net.ErrClosed) {
+				return nil
+			}
 			return fmt.Errorf("failed to accept connection: %w", err)
 		}
 
-		go s.handleConnection(conn)
+		s.mu.Lock()
+		s.open++
+		s.pending++
+		s.mu.Unlock()
+
+		go func() {
+			s.handleConnection(conn)
+
+			s.mu.Lock()
+			s.open--
+			s.idle.Broadcast()
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Drain stops accepting connections, ends event streams and waits for all
+// in-flight requests to finish. Requests arriving on already accepted
+// connections are rejected, unless the socket was handed off, in which case
+// they are served. It is safe to call more than once.
+func (s *Server) Drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.draining {
+		s.draining = true
+		s.listener.Close()
+		for conn := range s.streams {
+			conn.Close()
+		}
+	}
+
+	for s.active > 0 || s.pending > 0 {
+		s.idle.Wait()
 	}
 }
 
+// Handoff returns a duplicate of the listening socket for another process to
+// serve. Afterwards closing the listener no longer removes the socket file,
+// and connections accepted before Drain are still served while draining.
+func (s *Server) Handoff() (*os.File, error) {
+	listener, ok := s.listener.(*net.UnixListener)
+	if !ok {
+		return nil, fmt.Errorf("listener is not a unix socket")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return nil, fmt.Errorf("daemon is shutting down")
+	}
+
+	file, err := listener.File()
+	if err != nil {
+		return nil, fmt.Errorf("failed to duplicate listening socket: %w", err)
+	}
+	listener.SetUnlinkOnClose(false)
+	s.handoff = true
+	return file, nil
+}
+
+// Close drains the server and waits briefly for open connections, such as
+// the one that requested the shutdown, to finish writing their responses
 func (s *Server) Close() error {
-	return s.listener.Close()
+	s.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		s.mu.Lock()
+		for s.open > 0 {
+			s.idle.Wait()
+		}
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(closeWaitTimeout):
+		return fmt.Errorf("timed out waiting for %d connection(s) to close", s.openConnections())
+	}
+}
+
+func (s *Server) isDraining() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.draining
+}
+
+func (s *Server) openConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open
+}
+
+// received marks a connection's request as read. Requests that count as in
+// flight are registered atomically, so Drain can't miss them, and refused
+// once draining started unless the socket is being handed over.
+func (s *Server) received(counted bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending--
+	s.idle.Broadcast()
+	if !counted {
+		return true
+	}
+	if s.draining && !s.handoff {
+		return false
+	}
+	s.active++
+	return true
+}
+
+// endRequest unregisters an in-flight request admitted by received
+func (s *Server) endRequest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	s.idle.Broadcast()
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
@@ -132,19 +429,86 @@ func (s *Server) handleConnection(conn net.Conn) {
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
 
+	// Clients send their request right away; don't let an idle connection
+	// hold up Drain
+	conn.SetReadDeadline(time.Now().Add(requestReadTimeout))
+
 	var msg Message
-	if err := decoder.Decode(&msg); err != nil {
-		encoder.Encode(Response{Success: false, Error: "invalid message format"})
+	err := decoder.Decode(&msg)
+	conn.SetReadDeadline(time.Time{})
+
+	counted := err == nil && msg.Type != MessageTypeShutdown &&
+		msg.Type != MessageTypeRestart && msg.Type != MessageTypeSubscribe
+	if !s.received(counted) {
+		encoder.Encode(Response{Success: false, Error: "daemon is shutting down", Code: errcode.ShuttingDown})
+		return
+	}
+	if counted {
+		defer s.endRequest()
+	}
+
+	if err != nil {
+		s.log.Warn("Invalid message", "error", err)
+		encoder.Encode(Response{Success: false, Error: "invalid message format", Code: errcode.InvalidArgument})
+		return
+	}
+
+	s.log.Debug("Received request", "op", string(msg.Type))
+
+	if resp := checkProtocol(msg); resp != nil {
+		encoder.Encode(*resp)
+		return
+	}
+
+	caller, err := callerOf(conn)
+	if err != nil {
+		s.log.Warn("Failed to identify caller", "op", string(msg.Type), "error", err)
+		encoder.Encode(Response{Success: false, Error: "failed to identify caller", Code: errcode.Internal})
+		return
+	}
+	reqLog := s.log.With("op", string(msg.Type))
+	if caller != nil {
+		reqLog = reqLog.With("uid", caller.UID, "pid", caller.PID)
+	}
+	if authorizer, ok := s.handler.(Authorizer); ok && msg.Type != MessageTypeHello {
+		if err := authorizer.Authorize(caller, msg.Type); err != nil {
+			reqLog.Info("Request denied", "error", err)
+			encoder.Encode(ErrorResponse(err))
+			return
+		}
+	}
+
+	switch msg.Type {
+	case MessageTypeShutdown:
+		encoder.Encode(s.handler.HandleShutdown())
+		return
+	case MessageTypeRestart:
+		var req RestartRequest
+		if len(msg.Data) > 0 && json.Unmarshal(msg.Data, &req) != nil {
+			encoder.Encode(Response{Success: false, Error: "invalid restart request", Code: errcode.InvalidArgument})
+			return
+		}
+		req.Caller = caller
+		encoder.Encode(s.handler.HandleRestart(req))
 		return
 	}
 
 	var response Response
+	start := time.Now()
 
 	switch msg.Type {
+	case MessageTypeHello:
+		var req HelloRequest
+		if len(msg.Data) > 0 && json.Unmarshal(msg.Data, &req) != nil {
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
+		} else {
+			response = s.hello(req)
+		}
+
 	case MessageTypeRepoAdd:
 		var req RepoAddRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
 			response = s.handler.HandleRepoAdd(req)
 		}
@@ -155,88 +519,271 @@ func (s *Server) handleConnection(conn net.Conn) {
 	case MessageTypeRepoRemove:
 		var name string
 		if err := json.Unmarshal(msg.Data, &name); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
 			response = s.handler.HandleRepoRemove(name)
 		}
 
+	case MessageTypeRepoUpdate:
+		var req RepoUpdateRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
+		} else {
+			response = s.handler.HandleRepoUpdate(req)
+		}
+
 	case MessageTypeClaim:
 		var req ClaimRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
+			req.Caller = caller
 			response = s.handler.HandleClaim(req)
 		}
 
 	case MessageTypeRelease:
 		var req ReleaseRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
+			req.Caller = caller
 			response = s.handler.HandleRelease(req)
 		}
 
 	case MessageTypePoolStatus:
 		var req PoolStatusRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
+			req.Caller = caller
 			response = s.handler.HandlePoolStatus(req)
 		}
 
 	case MessageTypeDaemonStatus:
-		response = s.handler.HandleDaemonStatus()
+		response = s.handler.HandleDaemonStatus(DaemonStatusRequest{Caller: caller})
 
 	case MessageTypeWorktreeList:
-		response = s.handler.HandleWorktreeList()
+		response = s.handler.HandleWorktreeList(WorktreeListRequest{Caller: caller})
 
 	case MessageTypeRefresh:
 		var req RefreshRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
+			req.Caller = caller
 			response = s.handler.HandleRefresh(req)
 		}
 
 	case MessageTypeShow:
 		var req ShowRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
-			response = Response{Success: false, Error: "invalid request data"}
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
 		} else {
+			req.Caller = caller
 			response = s.handler.HandleShow(req)
 		}
 
+	case MessageTypeHistory:
+		var req HistoryRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
+		} else {
+			req.Caller = caller
+			response = s.handler.HandleHistory(req)
+		}
+
+	case MessageTypeStats:
+		var req StatsRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
+		} else {
+			req.Caller = caller
+			response = s.handler.HandleStats(req)
+		}
+
+	case MessageTypeLogLevel:
+		var req LogLevelRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			response = Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument}
+		} else {
+			response = s.handler.HandleLogLevel(req)
+		}
+
+	case MessageTypeReload:
+		response = s.handler.HandleReload()
+
+	case MessageTypeSubscribe:
+		var req SubscribeRequest
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &req); err != nil {
+				encoder.Encode(Response{Success: false, Error: "invalid request data", Code: errcode.InvalidArgument})
+				return
+			}
+		}
+		req.Caller = caller
+		s.streamEvents(conn, encoder, req)
+		return
+
 	default:
-		response = Response{Success: false, Error: "unknown message type"}
+		response = Response{Success: false, Error: fmt.Sprintf("%s '%s'", unknownMessageType, msg.Type),
+			Code: errcode.ProtocolMismatch}
+	}
+
+	log := reqLog.With("duration_ms", time.Since(start).Milliseconds())
+	if response.Success {
+		log.Debug("Handled request")
+	} else {
+		log.Info("Request failed", "error", response.Error)
 	}
 
-	encoder.Encode(response)
+	if err := encoder.Encode(response); err != nil {
+		log.Warn("Failed to write response", "error", err)
+	}
+}
+
+// streamEvents acknowledges a subscription and then writes one JSON event per
+// line until the subscription ends or the client disconnects
+func (s *Server) streamEvents(conn net.Conn, encoder *json.Encoder, req SubscribeRequest) {
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		encoder.Encode(Response{Success: false, Error: "daemon is shutting down", Code: errcode.ShuttingDown})
+		return
+	}
+	s.streams[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, conn)
+		s.mu.Unlock()
+	}()
+
+	sub := s.handler.HandleSubscribe(req)
+	defer sub.Close()
+
+	if err := encoder.Encode(Response{Success: true}); err != nil {
+		return
+	}
+
+	for _, e := range sub.Backlog {
+		if err := encoder.Encode(e); err != nil {
+			return
+		}
+	}
+
+	if sub.C == nil {
+		return
+	}
+
+	// Clients never write after subscribing, so any read completing means
+	// the connection has gone away
+	go func() {
+		io.Copy(io.Discard, conn)
+		sub.Close()
+	}()
+
+	for e := range sub.C {
+		if err := encoder.Encode(e); err != nil {
+			return
+		}
+	}
 }
 
 type Client struct {
 	socketPath string
+	// Timeout bounds a whole request/response exchange. Zero means no limit.
+	Timeout time.Duration
+	// AutoStart, if set, is called when no daemon is listening on the socket.
+	// It should start a daemon and return once it is ready; the request is
+	// then retried once.
+	AutoStart func() error
 }
 
 func NewClient(socketPath string) *Client {
 	return &Client{socketPath: socketPath}
 }
 
+// dial connects to the daemon, starting one first if the socket is missing
+// or stale and AutoStart is set
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil && c.AutoStart != nil && daemonAbsent(err) {
+		if startErr := c.AutoStart(); startErr != nil {
+			return nil, fmt.Errorf("failed to start daemon: %w", startErr)
+		}
+		conn, err = dialer.DialContext(ctx, "unix", c.socketPath)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errcode.Wrap(errcode.DaemonUnavailable, err, "failed to connect to daemon")
+	}
+	return conn, nil
+}
+
+// daemonAbsent reports whether a dial error means nobody is serving the
+// socket, as opposed to e.g. a permission problem
+func daemonAbsent(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// SendMessage sends a request and returns the daemon's response. A request
+// the daemon doesn't know because it is older than the client fails with a
+// *ProtocolError.
 func (c *Client) SendMessage(msg Message) (*Response, error) {
-	conn, err := net.Dial("unix", c.socketPath)
+	return c.SendMessageContext(context.Background(), msg)
+}
+
+// SendMessageContext is SendMessage with a context that bounds the whole
+// exchange. When ctx ends first, its error is returned. The daemon may still
+// complete a request whose response was abandoned.
+func (c *Client) SendMessageContext(ctx context.Context, msg Message) (*Response, error) {
+	msg.Version = ProtocolVersion
+
+	resp, err := c.roundTrip(ctx, msg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+		return nil, err
+	}
+	if !resp.Success && strings.HasPrefix(resp.Error, unknownMessageType) {
+		return nil, c.unsupported(ctx, msg.Type)
+	}
+	return resp, nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, msg Message) (*Response, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
 	}
 	defer conn.Close()
 
+	if c.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+	// Unblock the exchange as soon as ctx ends
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
 	encoder := json.NewEncoder(conn)
 	decoder := json.NewDecoder(conn)
 
 	if err := encoder.Encode(msg); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 
 	var response Response
 	if err := decoder.Decode(&response); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
@@ -257,6 +804,11 @@ func (c *Client) RepoRemove(name string) (*Response, error) {
 	return c.SendMessage(Message{Type: MessageTypeRepoRemove, Data: data})
 }
 
+func (c *Client) RepoUpdate(req RepoUpdateRequest) (*Response, error) {
+	data, _ := json.Marshal(req)
+	return c.SendMessage(Message{Type: MessageTypeRepoUpdate, Data: data})
+}
+
 func (c *Client) Claim(req ClaimRequest) (*Response, error) {
 	data, _ := json.Marshal(req)
 	return c.SendMessage(Message{Type: MessageTypeClaim, Data: data})
@@ -289,3 +841,82 @@ func (c *Client) Show(req ShowRequest) (*Response, error) {
 	data, _ := json.Marshal(req)
 	return c.SendMessage(Message{Type: MessageTypeShow, Data: data})
 }
+
+// Restart asks the daemon to hand its socket over to a new daemon process
+// running executable and then exit once in-flight requests have finished
+func (c *Client) Restart(executable string) (*Response, error) {
+	data, err := json.Marshal(RestartRequest{Executable: executable})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.SendMessage(Message{Type: MessageTypeRestart, Data: data})
+}
+
+// Shutdown asks the daemon to stop once in-flight requests have finished. The
+// response is sent after draining, so it can take as long as the slowest
+// in-flight claim or release.
+func (c *Client) Shutdown() (*Response, error) {
+	return c.SendMessage(Message{Type: MessageTypeShutdown})
+}
+
+func (c *Client) History(req HistoryRequest) (*Response, error) {
+	data, _ := json.Marshal(req)
+	return c.SendMessage(Message{Type: MessageTypeHistory, Data: data})
+}
+
+func (c *Client) Stats(req StatsRequest) (*Response, error) {
+	data, _ := json.Marshal(req)
+	return c.SendMessage(Message{Type: MessageTypeStats, Data: data})
+}
+
+func (c *Client) LogLevel(req LogLevelRequest) (*Response, error) {
+	data, _ := json.Marshal(req)
+	return c.SendMessage(Message{Type: MessageTypeLogLevel, Data: data})
+}
+
+// Reload asks the daemon to re-read config.yaml
+func (c *Client) Reload() (*Response, error) {
+	return c.SendMessage(Message{Type: MessageTypeReload})
+}
+
+// Subscribe streams pool events to fn until the daemon closes the stream or fn
+// returns an error. Without Follow only the recent event history is sent.
+func (c *Client) Subscribe(req SubscribeRequest, fn func(events.Event) error) error {
+	conn, err := c.dial(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	data, _ := json.Marshal(req)
+	msg := Message{Type: MessageTypeSubscribe, Version: ProtocolVersion, Data: data}
+	if err := json.NewEncoder(conn).Encode(msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	decoder := json.NewDecoder(conn)
+
+	var response Response
+	if err := decoder.Decode(&response); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if !response.Success {
+		if strings.HasPrefix(response.Error, unknownMessageType) {
+			return c.unsupported(context.Background(), MessageTypeSubscribe)
+		}
+		return fmt.Errorf("%s", response.Error)
+	}
+
+	for {
+		var e events.Event
+		if err := decoder.Decode(&e); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read event: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned by TryAcquire when another process holds the lock
+var ErrLocked = errors.New("lock is held by another process")
+
+// HeldError reports a lock held by another process, with the PID the holder
+// recorded via WritePID (0 if it recorded none). It matches ErrLocked.
+type HeldError struct {
+	Path string
+	PID  int
+}
+
+func (e *HeldError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by process %d", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another process", e.Path)
+}
+
+func (e *HeldError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Lock is an exclusive advisory lock on a file, held until Release is called
+// or the process exits
+type Lock struct {
+	file *os.File
+}
+
+// Acquire blocks until it holds an exclusive lock on path, creating the file
+// if needed
+func Acquire(path string) (*Lock, error) {
+	return acquire(path, syscall.LOCK_EX)
+}
+
+// TryAcquire takes an exclusive lock on path without waiting, returning a
+// *HeldError if another process holds it
+func TryAcquire(path string) (*Lock, error) {
+	return acquire(path, syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+func acquire(path string, how int) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := flock(file, how); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &HeldError{Path: path, PID: readPID(path)}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// WritePID records the current process ID in the lock file so that processes
+// failing to take the lock can report who holds it
+func (l *Lock) WritePID() error {
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate lock file: %w", err)
+	}
+	if _, err := l.file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Release unlocks and closes the lock file. The file itself is left in place
+// so that every process keeps locking the same inode.
+func (l *Lock) Release() error {
+	if err := flock(l.file, syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return l.file.Close()
+}
+
+func readPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+func flock(file *os.File, how int) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
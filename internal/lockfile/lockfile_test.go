@@ -0,0 +1,41 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryAcquireReportsHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.lock")
+
+	lock, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if err := lock.WritePID(); err != nil {
+		t.Fatalf("WritePID() error = %v", err)
+	}
+
+	// flock locks belong to the open file, so a second open conflicts even
+	// within one process
+	_, err = TryAcquire(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("second TryAcquire() error = %v, want ErrLocked", err)
+	}
+	var held *HeldError
+	if !errors.As(err, &held) || held.PID != os.Getpid() {
+		t.Fatalf("second TryAcquire() error = %#v, want holder pid %d", err, os.Getpid())
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	again, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire() after Release error = %v", err)
+	}
+	again.Release()
+}
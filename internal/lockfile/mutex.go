@@ -0,0 +1,36 @@
+package lockfile
+
+import "sync"
+
+// Mutex serializes work between goroutines and, through a lock file, between
+// processes. Unlike Lock it can be taken and released any number of times.
+type Mutex struct {
+	path string
+	mu   sync.Mutex
+	held *Lock
+}
+
+// NewMutex returns a mutex backed by the lock file at path
+func NewMutex(path string) *Mutex {
+	return &Mutex{path: path}
+}
+
+// Lock blocks until no other goroutine or process holds the mutex
+func (m *Mutex) Lock() error {
+	m.mu.Lock()
+	lock, err := Acquire(m.path)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.held = lock
+	return nil
+}
+
+// Unlock releases the mutex. Closing the lock file releases the lock even if
+// unlocking fails, so the error is not reported.
+func (m *Mutex) Unlock() {
+	m.held.Release()
+	m.held = nil
+	m.mu.Unlock()
+}
@@ -0,0 +1,61 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "daemon.log")
+
+	w, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:                "fourth\n",
+		BackupPath(path, 1): "third\n",
+		BackupPath(path, 2): "second\n",
+	}
+	for file, want := range expected {
+		got, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if string(got) != want {
+			t.Errorf("expected %s to contain %q, got %q", file, want, got)
+		}
+	}
+
+	if _, err := os.Stat(BackupPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups to be kept")
+	}
+}
+
+func TestWriterAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	w.Write([]byte("new\n"))
+	w.Close()
+
+	got, _ := os.ReadFile(path)
+	if string(got) != "old\nnew\n" {
+		t.Errorf("expected log to be appended to, got %q", got)
+	}
+}
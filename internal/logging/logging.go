@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Log formats accepted by Setup
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// level is shared by every handler Setup installs so it can be changed while
+// the daemon is running
+var level = new(slog.LevelVar)
+
+// Setup makes the default slog logger, and the standard log package, write
+// records in the given format to w
+func Setup(w io.Writer, format, lvl string) error {
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format '%s' (expected text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	// Messages from the log package are recorded at info level
+	log.SetFlags(0)
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level '%s' (expected debug, info, warn or error)", s)
+	}
+	return l, nil
+}
+
+// SetLevel changes the minimum level of records written. An empty level
+// means info.
+func SetLevel(s string) error {
+	if s == "" {
+		s = "info"
+	}
+	l, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level returns the current minimum level in lower case
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// Component returns a logger whose records carry the given component name.
+// Loggers should be created after Setup.
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSetupJSONWithRuntimeLevel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&buf, FormatJSON, "warn"); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	logger := Component("pool")
+	logger.Info("dropped")
+	logger.Warn("kept", "repo", "app")
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	logger.Debug("kept after level change")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(lines), buf.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatalf("record is not JSON: %v", err)
+	}
+	if record["component"] != "pool" || record["repo"] != "app" || record["level"] != "WARN" {
+		t.Errorf("record = %v, want component, repo and level fields", record)
+	}
+
+	if Level() != "debug" {
+		t.Errorf("Level() = %q, want debug", Level())
+	}
+	if err := SetLevel("loud"); err == nil {
+		t.Error("SetLevel(loud) succeeded, want error")
+	}
+}
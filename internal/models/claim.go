@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ClaimOutcome string
+
+const (
+	ClaimOutcomeActive   ClaimOutcome = "active"
+	ClaimOutcomeReleased ClaimOutcome = "released"
+	ClaimOutcomeCorrupt  ClaimOutcome = "corrupt"
+	// ClaimOutcomeRejected records a claim that failed because every
+	// worktree was claimed and the pool was at max_worktrees. It never held
+	// a worktree.
+	ClaimOutcomeRejected ClaimOutcome = "rejected"
+)
+
+// Claim is a single lease of a worktree, kept after release for auditing
+type Claim struct {
+	ID           uuid.UUID    `db:"id"`
+	WorktreeID   uuid.UUID    `db:"worktree_id"`
+	WorktreeName string       `db:"worktree_name"`
+	RepoID       uuid.UUID    `db:"repo_id"`
+	RepoName     string       `db:"repo_name"`
+	Branch       string       `db:"branch"`
+	Owner        string       `db:"owner"`
+	OwnerUID     *int         `db:"owner_uid"` // nil for claims made before uids were recorded
+	ClaimedAt    time.Time    `db:"claimed_at"`
+	ReleasedAt   *time.Time   `db:"released_at"`
+	StartSHA     string       `db:"start_sha"`
+	EndSHA       *string      `db:"end_sha"`
+	Outcome      ClaimOutcome `db:"outcome"`
+	LatencyMS    *int64       `db:"claim_latency_ms"` // time from request to checkout
+}
+
+// ClaimFilter narrows a claim history query. Zero values match everything.
+type ClaimFilter struct {
+	RepoName string
+	Branch   string
+	Since    *time.Time // claimed at or after
+	// ActiveSince matches claims that were held at any point after the given
+	// time, including ones claimed earlier and still active
+	ActiveSince *time.Time
+	// OwnerUID matches claims made by one user
+	OwnerUID *int
+	// Rejected includes claims turned away because the pool was at
+	// capacity, which are left out otherwise
+	Rejected bool
+	Limit    int
+}
+
+func NewClaim(repo *Repository, worktree *Worktree, branch, owner, startSHA string, latency time.Duration) *Claim {
+	claimedAt := time.Now()
+	if worktree.LeasedAt != nil {
+		claimedAt = *worktree.LeasedAt
+	}
+
+	latencyMS := latency.Milliseconds()
+
+	return &Claim{
+		ID:           uuid.New(),
+		WorktreeID:   worktree.ID,
+		WorktreeName: worktree.Name,
+		RepoID:       repo.ID,
+		RepoName:     repo.Name,
+		Branch:       branch,
+		Owner:        owner,
+		OwnerUID:     worktree.OwnerUID,
+		ClaimedAt:    claimedAt,
+		StartSHA:     startSHA,
+		Outcome:      ClaimOutcomeActive,
+		LatencyMS:    &latencyMS,
+	}
+}
+
+// NewRejectedClaim records a claim turned away because the pool was at capacity
+func NewRejectedClaim(repo *Repository, branch, owner string, ownerUID int) *Claim {
+	now := time.Now()
+	return &Claim{
+		ID:         uuid.New(),
+		RepoID:     repo.ID,
+		RepoName:   repo.Name,
+		Branch:     branch,
+		Owner:      owner,
+		OwnerUID:   &ownerUID,
+		ClaimedAt:  now,
+		ReleasedAt: &now,
+		Outcome:    ClaimOutcomeRejected,
+	}
+}
+
+// Duration returns how long the worktree was held, up to now for active claims
+func (c *Claim) Duration() time.Duration {
+	if c.ReleasedAt != nil {
+		return c.ReleasedAt.Sub(c.ClaimedAt)
+	}
+	return time.Since(c.ClaimedAt)
+}
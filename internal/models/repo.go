@@ -17,6 +17,15 @@ type Repository struct {
 	CreatedAt     time.Time  `db:"created_at"`
 }
 
+// FetchDue reports whether the reconciler should fetch the repository at now:
+// a fetch interval is set and it hasn't been fetched within it
+func (r *Repository) FetchDue(now time.Time) bool {
+	if r.FetchInterval <= 0 {
+		return false
+	}
+	return r.LastFetchTime == nil || now.Sub(*r.LastFetchTime) >= time.Duration(r.FetchInterval)*time.Minute
+}
+
 func NewRepository(name, path, baseBranch string, maxWorktrees, fetchInterval int) *Repository {
 	return &Repository{
 		ID:            uuid.New(),
@@ -32,3 +32,28 @@ func TestNewRepository(t *testing.T) {
 		t.Errorf("created_at should be recent")
 	}
 }
+
+func TestFetchDue(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name      string
+		interval  int
+		lastFetch *time.Time
+		want      bool
+	}{
+		{"no interval", 0, nil, false},
+		{"never fetched", 15, nil, true},
+		{"fetched within interval", 15, &recent, false},
+		{"interval elapsed", 5, &recent, true},
+	}
+
+	for _, tt := range tests {
+		repo := NewRepository("test-repo", "/path/to/repo", "main", 8, tt.interval)
+		repo.LastFetchTime = tt.lastFetch
+		if got := repo.FetchDue(now); got != tt.want {
+			t.Errorf("%s: FetchDue() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -15,16 +15,46 @@ type ReconcilerRun struct {
 
 type DaemonStatus struct {
 	Running        bool
+	Version        string
+	PID            int
+	StartedAt      time.Time
 	SocketPath     string
+	WorktreeDir    string
 	LastReconciler *time.Time
 	Repositories   int
 }
 
 type PoolStatus struct {
-	RepoName  string
-	Total     int
-	InUse     int
-	Idle      int
-	Max       int
-	LastFetch *time.Time
+	RepoName   string
+	Total      int
+	InUse      int
+	Idle       int
+	Corrupt    int
+	Max        int
+	LastFetch  *time.Time
+	BaseBranch string
+	BaseSHA    string // remote base branch tip as of the last fetch
+	// StaleIdle counts idle worktrees not at BaseSHA; MaxBehind is the
+	// largest number of commits any of them is behind
+	StaleIdle int
+	MaxBehind int
+}
+
+// HeldClaim is who holds a claimed worktree of a repository. OwnerUID is nil
+// and Owner empty for claims made before they were recorded.
+type HeldClaim struct {
+	RepoName string
+	OwnerUID *int
+	Owner    string
+}
+
+// QuotaUsage is how many claims one user or owner label holds against a
+// quota, in one repository or, with an empty Repo, in all of them
+type QuotaUsage struct {
+	Scope string `json:"scope"` // "user" or "label"
+	UID   *int   `json:"uid,omitempty"`
+	Label string `json:"label,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Used  int    `json:"used"`
+	Limit int    `json:"limit"`
 }
@@ -12,8 +12,30 @@ const (
 	WorktreeStatusIdle    WorktreeStatus = "idle"
 	WorktreeStatusInUse   WorktreeStatus = "in-use"
 	WorktreeStatusCorrupt WorktreeStatus = "corrupt"
+
+	// Transitional statuses are persisted before the git work of an operation
+	// begins, so an operation interrupted by a crash can be recovered
+	WorktreeStatusCreating  WorktreeStatus = "creating"
+	WorktreeStatusClaiming  WorktreeStatus = "claiming"
+	WorktreeStatusReleasing WorktreeStatus = "releasing"
+	WorktreeStatusDeleting  WorktreeStatus = "deleting"
 )
 
+// Transitional reports whether the status marks an operation in progress
+func (s WorktreeStatus) Transitional() bool {
+	switch s {
+	case WorktreeStatusCreating, WorktreeStatusClaiming, WorktreeStatusReleasing, WorktreeStatusDeleting:
+		return true
+	}
+	return false
+}
+
+// HoldsBranch reports whether a worktree in this status has a claimed branch
+// checked out
+func (s WorktreeStatus) HoldsBranch() bool {
+	return s == WorktreeStatusInUse || s == WorktreeStatusClaiming || s == WorktreeStatusReleasing
+}
+
 type Worktree struct {
 	ID        uuid.UUID      `db:"id"`
 	RepoID    uuid.UUID      `db:"repo_id"`
@@ -22,6 +44,7 @@ type Worktree struct {
 	Status    WorktreeStatus `db:"status"`
 	LeasedAt  *time.Time     `db:"leased_at"`
 	Branch    *string        `db:"branch"`
+	OwnerUID  *int           `db:"owner_uid"` // the claiming user while a branch is held, if known
 	CreatedAt time.Time      `db:"created_at"`
 }
 
@@ -2,50 +2,54 @@ package pool
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/google/uuid"
 )
 
 type Allocator struct {
+	worktreeDir string
+	log         *slog.Logger
 }
 
-func NewAllocator() *Allocator {
-	return &Allocator{}
+func NewAllocator(worktreeDir string) *Allocator {
+	return &Allocator{worktreeDir: worktreeDir, log: logging.Component("allocator")}
 }
 
-func (a *Allocator) CreateWorktree(repo *models.Repository) (*models.Worktree, error) {
-	// Generate unique worktree name
-	worktreeID := uuid.New()
-	worktreeName := worktreeID.String()
+// NewWorktree picks the name and path of a new worktree without creating it
+func (a *Allocator) NewWorktree(repo *models.Repository) *models.Worktree {
+	worktreeName := uuid.New().String()
+	worktreePath := filepath.Join(a.worktreeDir, repo.Name, worktreeName)
 
+	worktree := models.NewWorktree(repo.ID, worktreeName, worktreePath)
+	worktree.Status = models.WorktreeStatusCreating
+	return worktree
+}
+
+// CreateWorktree adds the git worktree for a worktree from NewWorktree
+func (a *Allocator) CreateWorktree(repo *models.Repository, worktree *models.Worktree) error {
 	// Create repository subdirectory if needed
-	repoWorkDir := filepath.Join(config.GetWorktreeDir(), repo.Name)
-	if err := os.MkdirAll(repoWorkDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create repo work directory: %w", err)
+	if err := os.MkdirAll(filepath.Dir(worktree.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create repo work directory: %w", err)
 	}
 
-	worktreePath := filepath.Join(repoWorkDir, worktreeName)
-
 	// Create git worktree
-	cmd := exec.Command("git", "-C", repo.Path, "worktree", "add", "--detach", worktreePath, repo.BaseBranch)
+	cmd := exec.Command("git", "-C", repo.Path, "worktree", "add", "--detach", worktree.Path, repo.BaseBranch)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("failed to create worktree: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to create worktree: %w\nOutput: %s", err, string(output))
 	}
 
-	// Create worktree model
-	worktree := models.NewWorktree(repo.ID, worktreeName, worktreePath)
-
-	log.Printf("[INFO] Created worktree: %s", worktreeName)
+	a.log.Info("Created worktree", "op", "create", "repo", repo.Name, "worktree", worktree.Name)
 
-	return worktree, nil
+	return nil
 }
 
 func (a *Allocator) CleanWorktree(worktree *models.Worktree) error {
@@ -69,7 +73,7 @@ func (a *Allocator) DeleteWorktree(repo *models.Repository, worktree *models.Wor
 	cmd := exec.Command("git", "-C", repo.Path, "worktree", "remove", worktree.Path, "--force")
 	if err := cmd.Run(); err != nil {
 		// If worktree command fails, try to remove directory directly
-		log.Printf("[WARN] Failed to remove worktree via git: %v", err)
+		a.log.Warn("Failed to remove worktree via git", "op", "delete", "repo", repo.Name, "worktree", worktree.Name, "error", err)
 	}
 
 	// Remove directory if it still exists
@@ -85,7 +89,7 @@ func (a *Allocator) DeleteWorktree(repo *models.Repository, worktree *models.Wor
 }
 
 func (a *Allocator) FetchRepository(repo *models.Repository) error {
-	log.Printf("[INFO] Fetching updates for repository '%s'", repo.Name)
+	a.log.Info("Fetching updates", "op", "fetch", "repo", repo.Name)
 
 	cmd := exec.Command("git", "-C", repo.Path, "fetch", "--all", "--prune")
 	if output, err := cmd.CombinedOutput(); err != nil {
@@ -116,20 +120,52 @@ func (a *Allocator) UpdateWorktree(repo *models.Repository, worktree *models.Wor
 		return fmt.Errorf("failed to update worktree to %s: %w\nOutput: %s", latestSHA, err, string(output))
 	}
 
-	log.Printf("[INFO] Updated worktree %s to commit %s", worktree.Name, latestSHA[:7])
+	a.log.Info("Updated worktree", "op", "update", "repo", repo.Name, "worktree", worktree.Name, "commit", latestSHA[:7])
 
 	return nil
 }
 
+// BaseSHA resolves the tip of the repository's remote base branch as of the
+// last fetch
+func (a *Allocator) BaseSHA(repo *models.Repository) (string, error) {
+	cmd := exec.Command("git", "-C", repo.Path, "rev-parse", fmt.Sprintf("origin/%s", repo.BaseBranch))
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve origin/%s: %w", repo.BaseBranch, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommitsBehind counts the commits reachable from sha but not from the
+// worktree's HEAD
+func (a *Allocator) CommitsBehind(worktree *models.Worktree, sha string) (int, error) {
+	cmd := exec.Command("git", "-C", worktree.Path, "rev-list", "--count", "HEAD.."+sha)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare worktree with %s: %w", sha, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// HeadSHA returns the commit currently checked out in a worktree
+func (a *Allocator) HeadSHA(worktree *models.Worktree) (string, error) {
+	cmd := exec.Command("git", "-C", worktree.Path, "rev-parse", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func (a *Allocator) ClaimWorktree(worktree *models.Worktree, branch string) (*models.Worktree, error) {
-	if worktree.Status != models.WorktreeStatusIdle {
-		return nil, fmt.Errorf("worktree is not idle")
+	if worktree.Status != models.WorktreeStatusClaiming {
+		return nil, fmt.Errorf("worktree is not being claimed")
 	}
 
 	// First, fetch to ensure we have the latest branches
 	cmd := exec.Command("git", "-C", worktree.Path, "fetch", "origin")
 	if err := cmd.Run(); err != nil {
-		log.Printf("[WARN] Failed to fetch before checkout: %v", err)
+		a.log.Warn("Failed to fetch before checkout", "op", "claim", "worktree", worktree.Name, "branch", branch, "error", err)
 	}
 
 	// Check if the branch exists locally or remotely
@@ -150,32 +186,42 @@ func (a *Allocator) ClaimWorktree(worktree *models.Worktree, branch string) (*mo
 	worktree.LeasedAt = &now
 	worktree.Branch = &branch
 
-	log.Printf("[INFO] Claimed worktree %s with branch %s", worktree.Name, branch)
+	a.log.Info("Claimed worktree", "op", "claim", "worktree", worktree.Name, "branch", branch)
 
 	return worktree, nil
 }
 
 func (a *Allocator) ReleaseWorktree(worktree *models.Worktree, repo *models.Repository) (*models.Worktree, error) {
-	if worktree.Status != models.WorktreeStatusInUse {
-		return nil, fmt.Errorf("worktree is not in use")
+	if worktree.Status != models.WorktreeStatusReleasing {
+		return nil, fmt.Errorf("worktree is not being released")
+	}
+
+	if err := a.ResetWorktree(repo, worktree); err != nil {
+		worktree.Status = models.WorktreeStatusCorrupt
+		return worktree, err
 	}
 
+	worktree.Status = models.WorktreeStatusIdle
+	worktree.LeasedAt = nil
+	worktree.Branch = nil
+
+	return worktree, nil
+}
+
+// ResetWorktree discards all changes in a worktree and detaches it at the tip
+// of the base branch, as it is when idle
+func (a *Allocator) ResetWorktree(repo *models.Repository, worktree *models.Worktree) error {
 	// Try to clean the worktree
 	if err := a.CleanWorktree(worktree); err != nil {
-		log.Printf("[ERROR] Failed to clean worktree '%s': %v", worktree.Name, err)
-		worktree.Status = models.WorktreeStatusCorrupt
-		return worktree, fmt.Errorf("worktree cleanup failed")
+		a.log.Error("Failed to clean worktree", "op", "reset", "repo", repo.Name, "worktree", worktree.Name, "error", err)
+		return fmt.Errorf("worktree cleanup failed")
 	}
 
 	// Checkout back to detached HEAD at the default branch
 	cmd := exec.Command("git", "-C", worktree.Path, "checkout", "--detach", fmt.Sprintf("origin/%s", repo.BaseBranch))
 	if output, err := cmd.CombinedOutput(); err != nil {
-		log.Printf("[WARN] Failed to detach HEAD: %v\nOutput: %s", err, string(output))
+		a.log.Warn("Failed to detach HEAD", "op", "reset", "repo", repo.Name, "worktree", worktree.Name, "error", err, "output", string(output))
 	}
 
-	worktree.Status = models.WorktreeStatusIdle
-	worktree.LeasedAt = nil
-	worktree.Branch = nil
-
-	return worktree, nil
+	return nil
 }
@@ -0,0 +1,27 @@
+package pool
+
+import "github.com/albertywu/gitpool/internal/config"
+
+// QuotasFromConfig converts the quotas in config.yaml for the pool
+func QuotasFromConfig(q config.Quotas) Quotas {
+	quotas := Quotas{
+		QuotaLimits: QuotaLimits{PerUser: q.PerUser, PerLabel: q.PerLabel},
+		Repos:       make(map[string]QuotaLimits, len(q.Repos)),
+	}
+	for repo, limits := range q.Repos {
+		quotas.Repos[repo] = QuotaLimits{PerUser: limits.PerUser, PerLabel: limits.PerLabel}
+	}
+	return quotas
+}
+
+// HooksFromConfig converts the hooks in config.yaml for the pool
+func HooksFromConfig(h config.Hooks) Hooks {
+	hooks := Hooks{
+		HookCommands: HookCommands{PostCreate: h.PostCreate, PostClaim: h.PostClaim},
+		Repos:        make(map[string]HookCommands, len(h.Repos)),
+	}
+	for repo, commands := range h.Repos {
+		hooks.Repos[repo] = HookCommands{PostCreate: commands.PostCreate, PostClaim: commands.PostClaim}
+	}
+	return hooks
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// hookTimeout bounds how long a hook may run before it is killed
+const hookTimeout = 10 * time.Minute
+
+// HookCommands holds the shell commands run in a worktree. Empty means none.
+type HookCommands struct {
+	// PostCreate runs after the pool adds a worktree, before it can be
+	// claimed
+	PostCreate string
+	// PostClaim runs after a worktree was checked out for a claim, before
+	// the claim returns
+	PostClaim string
+}
+
+// Hooks are the hooks the pool runs. The global commands apply to all
+// repositories; those in Repos replace them for one repository.
+type Hooks struct {
+	HookCommands
+	// Repos is keyed by repository name, compared case-insensitively
+	Repos map[string]HookCommands
+}
+
+// SetHooks replaces the hooks run by later operations
+func (p *Pool) SetHooks(h Hooks) {
+	p.hooks.Store(&h)
+}
+
+// repoHooks returns the hooks of a repository
+func (h *Hooks) repoHooks(repo string) HookCommands {
+	commands := h.HookCommands
+	for name, override := range h.Repos {
+		if !strings.EqualFold(name, repo) {
+			continue
+		}
+		if override.PostCreate != "" {
+			commands.PostCreate = override.PostCreate
+		}
+		if override.PostClaim != "" {
+			commands.PostClaim = override.PostClaim
+		}
+	}
+	return commands
+}
+
+// runHook runs the named hook of a repository in a worktree with sh. The
+// worktree is described in GITPOOL_* environment variables. Failures are
+// logged and don't fail the operation that ran the hook.
+func (p *Pool) runHook(hook string, repo *models.Repository, wt *models.Worktree) {
+	h := p.hooks.Load()
+	if h == nil {
+		return
+	}
+	var command string
+	switch commands := h.repoHooks(repo.Name); hook {
+	case "post_create":
+		command = commands.PostCreate
+	case "post_claim":
+		command = commands.PostClaim
+	}
+	if command == "" {
+		return
+	}
+
+	var branch string
+	if wt.Branch != nil {
+		branch = *wt.Branch
+	}
+	log := p.log.With("op", "hook", "hook", hook, "repo", repo.Name, "worktree", wt.Name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = wt.Path
+	cmd.Env = append(os.Environ(),
+		"GITPOOL_HOOK="+hook,
+		"GITPOOL_REPO="+repo.Name,
+		"GITPOOL_REPO_PATH="+repo.Path,
+		"GITPOOL_WORKTREE_ID="+wt.Name,
+		"GITPOOL_WORKTREE_PATH="+wt.Path,
+		"GITPOOL_BRANCH="+branch,
+	)
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Warn("Hook failed", "error", err, "output", strings.TrimSpace(string(output)))
+		return
+	}
+	log.Debug("Hook finished", "duration", time.Since(start).String())
+}
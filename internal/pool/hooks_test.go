@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepoHooks(t *testing.T) {
+	hooks := &Hooks{
+		HookCommands: HookCommands{PostCreate: "make deps", PostClaim: "make env"},
+		Repos: map[string]HookCommands{
+			"web-app": {PostCreate: "npm ci"},
+		},
+	}
+
+	tests := []struct {
+		repo string
+		want HookCommands
+	}{
+		{"api", HookCommands{PostCreate: "make deps", PostClaim: "make env"}},
+		{"Web-App", HookCommands{PostCreate: "npm ci", PostClaim: "make env"}},
+	}
+	for _, tt := range tests {
+		if got := hooks.repoHooks(tt.repo); got != tt.want {
+			t.Errorf("repoHooks(%q) = %+v, want %+v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestHooksRun(t *testing.T) {
+	p, repo := newTestPool(t, 1)
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	p.SetHooks(Hooks{HookCommands: HookCommands{
+		PostCreate: `echo "create $GITPOOL_REPO $(git rev-parse --abbrev-ref HEAD)" >> ` + log,
+		PostClaim:  `echo "claim $GITPOOL_BRANCH $(git rev-parse --abbrev-ref HEAD)" >> ` + log + `; exit 1`,
+	}})
+
+	// A failing hook doesn't fail the claim
+	if _, err := p.ClaimWorktree(repo.Name, "feature", ClaimOptions{}); err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "create app HEAD\nclaim feature feature\n"
+	if got := string(data); got != want {
+		t.Errorf("hooks ran %q, want %q", strings.TrimSpace(got), want)
+	}
+}
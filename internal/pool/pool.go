@@ -1,12 +1,18 @@
 package pool
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"sync"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/albertywu/gitpool/internal/models"
 	"github.com/google/uuid"
 )
@@ -14,84 +20,191 @@ import (
 type Pool struct {
 	store     *db.Store
 	allocator *Allocator
-	mu        sync.Mutex
+	events    *events.Bus
+	log       *slog.Logger
+	// mu is held for every change to worktrees, including the git work. It
+	// is shared through a lock file with every process using the worktree
+	// directory, so a transitional status never belongs to a live operation
+	// while it is held.
+	mu *lockfile.Mutex
+	// quotas is nil until SetQuotas is called
+	quotas atomic.Pointer[Quotas]
+	// hooks is nil until SetHooks is called
+	hooks atomic.Pointer[Hooks]
 }
 
-func NewPool(store *db.Store) *Pool {
+// NewPool returns a pool creating worktrees in worktreeDir. lock must be
+// backed by LockFile(worktreeDir).
+func NewPool(store *db.Store, bus *events.Bus, worktreeDir string, lock *lockfile.Mutex) *Pool {
 	return &Pool{
 		store:     store,
-		allocator: NewAllocator(),
+		allocator: NewAllocator(worktreeDir),
+		events:    bus,
+		log:       logging.Component("pool"),
+		mu:        lock,
 	}
 }
 
-func (p *Pool) ClaimWorktree(repoName string, branch string) (*models.Worktree, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// LockFile returns the lock file that serializes changes to the pool in
+// worktreeDir between processes
+func LockFile(worktreeDir string) string {
+	return filepath.Join(worktreeDir, "pool.lock")
+}
 
-	// Get repository
-	repo, err := p.store.GetRepository(repoName)
-	if err != nil {
-		return nil, fmt.Errorf("repository '%s' not found", repoName)
-	}
+// ClaimOptions carries optional metadata about who is claiming a worktree
+type ClaimOptions struct {
+	Owner string
+	// OwnerUID is the user claiming the worktree, who may release it
+	OwnerUID int
+}
 
-	// Check if branch is already in use
-	inUse, err := p.store.IsBranchInUseForRepo(repo.ID, branch)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check branch availability: %w", err)
+// ReleaseOptions controls a release
+type ReleaseOptions struct {
+	// Authorize, if set, is called with the worktree and its repository
+	// while the pool is locked, and fails the release if it returns an error
+	Authorize func(*models.Worktree, *models.Repository) error
+}
+
+func (p *Pool) ClaimWorktree(repoName string, branch string, opts ClaimOptions) (*models.Worktree, error) {
+	if err := p.mu.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock pool: %w", err)
 	}
-	if inUse {
-		return nil, fmt.Errorf("branch '%s' is already in use by another worktree in this repository", branch)
+	worktree, repo, err := p.claimWorktree(repoName, branch, opts)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
 	}
 
-	// Get idle worktrees
-	idleWorktrees, err := p.store.ListIdleWorktreesByRepo(repo.ID)
+	// The worktree is claimed, so the hook can take its time without
+	// holding up the rest of the pool
+	p.runHook("post_claim", repo, worktree)
+	return worktree, nil
+}
+
+// claimWorktree claims a worktree while the pool is locked
+func (p *Pool) claimWorktree(repoName string, branch string, opts ClaimOptions) (*models.Worktree, *models.Repository, error) {
+	requestedAt := time.Now()
+
+	// Get repository
+	repo, err := p.store.GetRepository(repoName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+		return nil, nil, errcode.New(errcode.RepoNotFound, "repository '%s' not found", repoName)
 	}
 
-	if len(idleWorktrees) == 0 {
-		log.Printf("[INFO] No available worktrees for '%s'. Waiting...", repoName)
+	// Reserve an idle worktree before touching git, so the worktree and the
+	// branch can't be claimed twice even by another process using the database
+	leasedAt := time.Now()
+	worktree, err := p.reserveWorktree(repo, branch, leasedAt, opts)
+	if errors.Is(err, errNoIdleWorktree) {
+		p.log.Info("No available worktrees", "op", "claim", "repo", repoName, "branch", branch)
 
 		// Trigger creation of new worktree if under capacity
 		worktrees, _ := p.store.ListWorktreesByRepo(repo.ID)
-		if len(worktrees) < repo.MaxWorktrees {
-			log.Printf("[INFO] Triggering Reconciler to create a new worktree...")
-			// In a real implementation, this would signal the reconciler
-			// For now, we'll create one directly
-			if err := p.createWorktree(repo); err != nil {
-				return nil, fmt.Errorf("failed to create worktree: %w", err)
+		if len(worktrees) >= repo.MaxWorktrees {
+			// Kept in the claim history as demand the pool couldn't serve
+			if err := p.store.CreateClaim(models.NewRejectedClaim(repo, branch, opts.Owner, opts.OwnerUID)); err != nil {
+				p.log.Error("Failed to record rejected claim", "op", "claim", "repo", repoName, "branch", branch, "error", err)
 			}
+			return nil, nil, errcode.New(errcode.PoolAtCapacity, "no available worktrees and pool is at capacity")
+		}
 
-			// Get the newly created worktree
-			idleWorktrees, err = p.store.ListIdleWorktreesByRepo(repo.ID)
-			if err != nil || len(idleWorktrees) == 0 {
-				return nil, fmt.Errorf("failed to get newly created worktree")
-			}
-		} else {
-			return nil, fmt.Errorf("no available worktrees and pool is at capacity")
+		p.log.Info("Creating a worktree for claim", "op", "claim", "repo", repoName, "branch", branch)
+		if err := p.createWorktree(repo); err != nil {
+			return nil, nil, fmt.Errorf("failed to create worktree: %w", err)
 		}
+
+		worktree, err = p.reserveWorktree(repo, branch, leasedAt, opts)
+		if errors.Is(err, errNoIdleWorktree) {
+			return nil, nil, fmt.Errorf("failed to get newly created worktree")
+		}
+	}
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Claim the first idle worktree
-	worktree := idleWorktrees[0]
 	claimedWorktree, err := p.allocator.ClaimWorktree(worktree, branch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to claim worktree: %w", err)
+		// The checkout didn't happen, so the worktree is still usable
+		if err := p.store.TransitionWorktree(worktree.ID.String(), models.WorktreeStatusClaiming,
+			models.WorktreeStatusIdle, nil, nil); err != nil {
+			p.log.Error("Failed to return worktree after failed claim", "op", "claim", "repo", repoName,
+				"worktree", worktree.Name, "branch", branch, "error", err)
+		}
+		return nil, nil, fmt.Errorf("failed to claim worktree: %w", err)
 	}
+	claimedWorktree.LeasedAt = &leasedAt
 
-	// The branch is already set by ClaimWorktree
-
-	// Update database with status and branch
-	if err := p.store.UpdateWorktreeStatusAndBranch(claimedWorktree.ID.String(),
-		claimedWorktree.Status, claimedWorktree.LeasedAt, claimedWorktree.Branch); err != nil {
-		return nil, fmt.Errorf("failed to update worktree status: %w", err)
+	if err := p.store.TransitionWorktree(claimedWorktree.ID.String(), models.WorktreeStatusClaiming,
+		models.WorktreeStatusInUse, claimedWorktree.LeasedAt, claimedWorktree.Branch); err != nil {
+		return nil, nil, fmt.Errorf("failed to update worktree status: %w", err)
 	}
 
-	return claimedWorktree, nil
+	p.recordClaim(repo, claimedWorktree, branch, opts.Owner, time.Since(requestedAt))
+
+	p.events.Publish(events.Event{
+		Type:       events.EventWorktreeClaimed,
+		Repo:       repo.Name,
+		WorktreeID: claimedWorktree.Name,
+		Branch:     branch,
+	})
+
+	return claimedWorktree, repo, nil
 }
 
-func (p *Pool) ReleaseWorktree(worktreeID string) error {
-	p.mu.Lock()
+var errNoIdleWorktree = errors.New("no idle worktree")
+
+// reserveWorktree marks the first idle worktree of a repository as being
+// claimed for branch, checking in the same transaction that the branch isn't
+// claimed yet and that the claim is within quota
+func (p *Pool) reserveWorktree(repo *models.Repository, branch string, leasedAt time.Time, opts ClaimOptions) (*models.Worktree, error) {
+	var reserved *models.Worktree
+	err := p.store.WithTx(func(tx *db.Store) error {
+		inUse, err := tx.IsBranchInUseForRepo(repo.ID, branch)
+		if err != nil {
+			return fmt.Errorf("failed to check branch availability: %w", err)
+		}
+		if inUse {
+			return errcode.New(errcode.BranchInUse, "branch '%s' is already in use by another worktree in this repository", branch)
+		}
+
+		if quotas := p.quotas.Load(); quotas != nil {
+			held, err := tx.ListHeldClaims()
+			if err != nil {
+				return fmt.Errorf("failed to check quotas: %w", err)
+			}
+			if err := checkQuotas(quotas, held, repo.Name, opts.OwnerUID, opts.Owner); err != nil {
+				return err
+			}
+		}
+
+		idleWorktrees, err := tx.ListIdleWorktreesByRepo(repo.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list worktrees: %w", err)
+		}
+		if len(idleWorktrees) == 0 {
+			return errNoIdleWorktree
+		}
+
+		worktree := idleWorktrees[0]
+		if err := tx.TransitionWorktree(worktree.ID.String(), models.WorktreeStatusIdle,
+			models.WorktreeStatusClaiming, &leasedAt, &branch); err != nil {
+			return fmt.Errorf("failed to reserve worktree: %w", err)
+		}
+		if err := tx.SetWorktreeOwner(worktree.ID.String(), opts.OwnerUID, opts.Owner); err != nil {
+			return fmt.Errorf("failed to record worktree owner: %w", err)
+		}
+		worktree.Status = models.WorktreeStatusClaiming
+		worktree.OwnerUID = &opts.OwnerUID
+		reserved = worktree
+		return nil
+	})
+	return reserved, err
+}
+
+func (p *Pool) ReleaseWorktree(worktreeID string, opts ReleaseOptions) error {
+	if err := p.mu.Lock(); err != nil {
+		return fmt.Errorf("failed to lock pool: %w", err)
+	}
 	defer p.mu.Unlock()
 
 	// Get worktree by name or ID
@@ -100,7 +213,7 @@ func (p *Pool) ReleaseWorktree(worktreeID string) error {
 		// Try by ID
 		worktree, err = p.store.GetWorktree(worktreeID)
 		if err != nil {
-			return fmt.Errorf("worktree '%s' not found", worktreeID)
+			return errcode.New(errcode.WorktreeNotFound, "worktree '%s' not found", worktreeID)
 		}
 	}
 
@@ -110,30 +223,103 @@ func (p *Pool) ReleaseWorktree(worktreeID string) error {
 		return fmt.Errorf("failed to get repository: %w", err)
 	}
 
-	log.Printf("[INFO] Releasing worktree '%s'", worktree.Name)
-	log.Printf("[INFO] Cleaning worktree: git reset --hard, git clean -fdx")
+	if opts.Authorize != nil {
+		if err := opts.Authorize(worktree, repo); err != nil {
+			return err
+		}
+	}
+
+	var branch string
+	if worktree.Branch != nil {
+		branch = *worktree.Branch
+	}
+
+	log := p.log.With("op", "release", "repo", repo.Name, "worktree", worktree.Name, "branch", branch)
+	log.Info("Releasing worktree")
+
+	// Capture where the claimant left the worktree before it is cleaned
+	var endSHA *string
+	if sha, err := p.allocator.HeadSHA(worktree); err == nil {
+		endSHA = &sha
+	}
+
+	// Record the release before touching git, unless someone else released
+	// or removed the worktree meanwhile
+	if worktree.Status != models.WorktreeStatusInUse {
+		return errcode.New(errcode.InvalidArgument, "failed to release worktree: worktree is %s, not in use", worktree.Status)
+	}
+	if err := p.store.TransitionWorktree(worktree.ID.String(), models.WorktreeStatusInUse,
+		models.WorktreeStatusReleasing, worktree.LeasedAt, worktree.Branch); err != nil {
+		return fmt.Errorf("failed to update worktree status: %w", err)
+	}
+	worktree.Status = models.WorktreeStatusReleasing
 
 	// Release worktree
 	releasedWorktree, err := p.allocator.ReleaseWorktree(worktree, repo)
 	if err != nil {
 		// Mark as corrupt if cleanup failed
-		p.store.UpdateWorktreeStatus(worktree.ID.String(), models.WorktreeStatusCorrupt, nil)
-		log.Printf("[INFO] Scheduling deletion and replacement of corrupted worktree")
-		return fmt.Errorf("failed to release worktree: %w", err)
+		p.markCorrupt(worktree, models.WorktreeStatusReleasing)
+		p.finishClaim(worktree, endSHA, models.ClaimOutcomeCorrupt)
+		log.Warn("Worktree is corrupt, scheduling deletion and replacement", "error", err)
+		p.events.Publish(events.Event{
+			Type:       events.EventWorktreeCorrupted,
+			Repo:       repo.Name,
+			WorktreeID: worktree.Name,
+			Branch:     branch,
+		})
+		return errcode.Wrap(errcode.WorktreeCorrupt, err, "failed to release worktree")
 	}
 
 	// Branch is already cleared by ReleaseWorktree
 
 	// Update database
-	if err := p.store.UpdateWorktreeStatusAndBranch(releasedWorktree.ID.String(),
+	if err := p.store.TransitionWorktree(releasedWorktree.ID.String(), models.WorktreeStatusReleasing,
 		releasedWorktree.Status, releasedWorktree.LeasedAt, releasedWorktree.Branch); err != nil {
 		return fmt.Errorf("failed to update worktree status: %w", err)
 	}
 
-	log.Printf("[INFO] Worktree returned to pool")
+	p.finishClaim(releasedWorktree, endSHA, models.ClaimOutcomeReleased)
+
+	p.events.Publish(events.Event{
+		Type:       events.EventWorktreeReleased,
+		Repo:       repo.Name,
+		WorktreeID: releasedWorktree.Name,
+		Branch:     branch,
+	})
+
+	log.Info("Worktree returned to pool")
 	return nil
 }
 
+// recordClaim adds a claim to the history. History is best-effort and never
+// fails the claim itself.
+func (p *Pool) recordClaim(repo *models.Repository, worktree *models.Worktree, branch, owner string, latency time.Duration) {
+	startSHA, err := p.allocator.HeadSHA(worktree)
+	if err != nil {
+		p.log.Warn("Failed to resolve start commit", "op", "claim", "repo", repo.Name, "worktree", worktree.Name, "branch", branch, "error", err)
+	}
+
+	claim := models.NewClaim(repo, worktree, branch, owner, startSHA, latency)
+	if err := p.store.CreateClaim(claim); err != nil {
+		p.log.Error("Failed to record claim", "op", "claim", "repo", repo.Name, "worktree", worktree.Name, "branch", branch, "error", err)
+	}
+}
+
+func (p *Pool) finishClaim(worktree *models.Worktree, endSHA *string, outcome models.ClaimOutcome) {
+	if err := p.store.FinishClaim(worktree.ID, time.Now(), endSHA, outcome); err != nil {
+		p.log.Error("Failed to record release", "op", "release", "worktree", worktree.Name, "error", err)
+	}
+}
+
+// PruneClaimHistory deletes finished claims older than the retention period.
+// A non-positive retention keeps history forever.
+func (p *Pool) PruneClaimHistory(retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	return p.store.PruneClaims(time.Now().Add(-retention))
+}
+
 func (p *Pool) GetPoolStatus(repoName string) ([]*models.PoolStatus, error) {
 	var repos []*models.Repository
 	var err error
@@ -141,7 +327,7 @@ func (p *Pool) GetPoolStatus(repoName string) ([]*models.PoolStatus, error) {
 	if repoName != "" {
 		repo, err := p.store.GetRepository(repoName)
 		if err != nil {
-			return nil, fmt.Errorf("repository '%s' not found", repoName)
+			return nil, errcode.New(errcode.RepoNotFound, "repository '%s' not found", repoName)
 		}
 		repos = []*models.Repository{repo}
 	} else {
@@ -159,60 +345,205 @@ func (p *Pool) GetPoolStatus(repoName string) ([]*models.PoolStatus, error) {
 		}
 
 		total := 0
-		inUse := counts[models.WorktreeStatusInUse]
-		idle := counts[models.WorktreeStatusIdle]
 		for _, count := range counts {
 			total += count
 		}
 
-		// Get last fetch time (would be tracked in real implementation)
-		lastFetch := time.Now().Add(-time.Duration(repo.FetchInterval) * time.Minute)
-
 		status := &models.PoolStatus{
-			RepoName:  repo.Name,
-			Total:     total,
-			InUse:     inUse,
-			Idle:      idle,
-			Max:       repo.MaxWorktrees,
-			LastFetch: &lastFetch,
+			RepoName:   repo.Name,
+			Total:      total,
+			InUse:      counts[models.WorktreeStatusInUse],
+			Idle:       counts[models.WorktreeStatusIdle],
+			Corrupt:    counts[models.WorktreeStatusCorrupt],
+			Max:        repo.MaxWorktrees,
+			LastFetch:  repo.LastFetchTime,
+			BaseBranch: repo.BaseBranch,
 		}
 
+		p.fillFreshness(repo, status)
+
 		statuses = append(statuses, status)
 	}
 
 	return statuses, nil
 }
 
+// fillFreshness records the remote base SHA and how far idle worktrees lag
+// behind it. Failures only leave the fields empty.
+func (p *Pool) fillFreshness(repo *models.Repository, status *models.PoolStatus) {
+	baseSHA, err := p.allocator.BaseSHA(repo)
+	if err != nil {
+		p.log.Warn("Failed to resolve base branch", "op", "status", "repo", repo.Name, "error", err)
+		return
+	}
+	status.BaseSHA = baseSHA
+
+	idleWorktrees, err := p.store.ListIdleWorktreesByRepo(repo.ID)
+	if err != nil {
+		return
+	}
+
+	for _, wt := range idleWorktrees {
+		behind, err := p.allocator.CommitsBehind(wt, baseSHA)
+		if err != nil {
+			p.log.Warn("Failed to check worktree freshness", "op", "status", "repo", repo.Name, "worktree", wt.Name, "error", err)
+			continue
+		}
+		if behind > 0 {
+			status.StaleIdle++
+		}
+		if behind > status.MaxBehind {
+			status.MaxBehind = behind
+		}
+	}
+}
+
+// createWorktree records a new worktree as being created before adding it
+// with git, so a crash in between doesn't leave an untracked directory
 func (p *Pool) createWorktree(repo *models.Repository) error {
-	worktree, err := p.allocator.CreateWorktree(repo)
+	worktree := p.allocator.NewWorktree(repo)
+	if err := p.store.CreateWorktree(worktree); err != nil {
+		return err
+	}
+
+	if err := p.allocator.CreateWorktree(repo, worktree); err != nil {
+		p.discardWorktree(repo, worktree)
+		return err
+	}
+	p.runHook("post_create", repo, worktree)
+
+	if err := p.store.TransitionWorktree(worktree.ID.String(), models.WorktreeStatusCreating,
+		models.WorktreeStatusIdle, nil, nil); err != nil {
+		return fmt.Errorf("failed to update worktree status: %w", err)
+	}
+
+	p.events.Publish(events.Event{
+		Type:       events.EventWorktreeCreated,
+		Repo:       repo.Name,
+		WorktreeID: worktree.Name,
+	})
+
+	return nil
+}
+
+// shrinkPool deletes up to excess idle worktrees after the pool size was
+// lowered. Claimed worktrees are never touched; they are removed by a later
+// run once released.
+func (p *Pool) shrinkPool(repo *models.Repository, worktrees []*models.Worktree, excess int) int {
+	removed := 0
+	for _, wt := range worktrees {
+		if removed == excess {
+			break
+		}
+		if wt.Status != models.WorktreeStatusIdle {
+			continue
+		}
+		if err := p.deleteWorktree(repo, wt); err != nil {
+			p.log.Error("Failed to delete excess worktree", "op", "shrink", "repo", repo.Name, "worktree", wt.Name, "error", err)
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		p.log.Info("Shrunk pool", "op", "shrink", "repo", repo.Name, "removed", removed, "max_worktrees", repo.MaxWorktrees)
+	}
+	return removed
+}
+
+// RebaseIdleWorktrees moves every idle worktree to the tip of the
+// repository's base branch, e.g. after the base branch was changed
+func (p *Pool) RebaseIdleWorktrees(repo *models.Repository) error {
+	if err := p.mu.Lock(); err != nil {
+		return fmt.Errorf("failed to lock pool: %w", err)
+	}
+	defer p.mu.Unlock()
+
+	if err := p.allocator.FetchRepository(repo); err != nil {
+		return err
+	}
+
+	idleWorktrees, err := p.store.ListIdleWorktreesByRepo(repo.ID)
 	if err != nil {
+		return fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	rebased := 0
+	for _, wt := range idleWorktrees {
+		if err := p.allocator.UpdateWorktree(repo, wt); err != nil {
+			p.log.Error("Failed to rebase worktree, marking it corrupt", "op", "rebase", "repo", repo.Name,
+				"worktree", wt.Name, "base_branch", repo.BaseBranch, "error", err)
+			p.markCorrupt(wt, models.WorktreeStatusIdle)
+			continue
+		}
+		rebased++
+	}
+
+	p.log.Info("Rebased idle worktrees", "op", "rebase", "repo", repo.Name,
+		"base_branch", repo.BaseBranch, "count", rebased, "failed", len(idleWorktrees)-rebased)
+	return nil
+}
+
+// markCorrupt flags a worktree for deletion by the reconciler, unless its
+// status changed since it was read
+func (p *Pool) markCorrupt(wt *models.Worktree, from models.WorktreeStatus) {
+	if err := p.store.TransitionWorktree(wt.ID.String(), from, models.WorktreeStatusCorrupt, nil, nil); err != nil {
+		p.log.Warn("Failed to mark worktree corrupt", "worktree", wt.Name, "error", err)
+	}
+}
+
+// deleteWorktree removes an idle or corrupt worktree from disk and the
+// database. It is marked as being deleted first, so it can't be claimed
+// meanwhile; if removing it from disk fails it is marked corrupt and the
+// reconciler retries.
+func (p *Pool) deleteWorktree(repo *models.Repository, wt *models.Worktree) error {
+	if err := p.store.TransitionWorktree(wt.ID.String(), wt.Status, models.WorktreeStatusDeleting, nil, nil); err != nil {
+		return fmt.Errorf("failed to mark worktree for deletion: %w", err)
+	}
+
+	if err := p.allocator.DeleteWorktree(repo, wt); err != nil {
+		p.markCorrupt(wt, models.WorktreeStatusDeleting)
 		return err
 	}
 
-	return p.store.CreateWorktree(worktree)
+	p.store.DeleteWorktree(wt.ID.String())
+	p.events.Publish(events.Event{
+		Type:       events.EventWorktreeDeleted,
+		Repo:       repo.Name,
+		WorktreeID: wt.Name,
+	})
+
+	return nil
 }
 
 func (p *Pool) CreateInitialWorktrees(repo *models.Repository, count int) error {
-	log.Printf("[INFO] Creating initial worktrees...")
+	if err := p.mu.Lock(); err != nil {
+		return fmt.Errorf("failed to lock pool: %w", err)
+	}
+	defer p.mu.Unlock()
+
+	p.log.Info("Creating initial worktrees", "op", "create", "repo", repo.Name, "count", count)
 
 	created := 0
 	for i := 0; i < count && i < repo.MaxWorktrees; i++ {
 		if err := p.createWorktree(repo); err != nil {
-			log.Printf("[ERROR] Failed to create worktree: %v", err)
+			p.log.Error("Failed to create worktree", "op", "create", "repo", repo.Name, "error", err)
 			continue
 		}
 		created++
 	}
 
 	if created > 0 {
-		log.Printf("[INFO] Created %d worktree(s)", created)
+		p.log.Info("Created worktrees", "op", "create", "repo", repo.Name, "created", created)
 	}
 
 	return nil
 }
 
 func (p *Pool) ReconcileWorktrees(repo *models.Repository) (*models.ReconcilerRun, error) {
-	p.mu.Lock()
+	if err := p.mu.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock pool: %w", err)
+	}
 	defer p.mu.Unlock()
 
 	run := &models.ReconcilerRun{
@@ -243,25 +574,28 @@ func (p *Pool) ReconcileWorktrees(repo *models.Repository) (*models.ReconcilerRu
 	// Clean up corrupt worktrees
 	for _, wt := range worktrees {
 		if wt.Status == models.WorktreeStatusCorrupt {
-			if err := p.allocator.DeleteWorktree(repo, wt); err != nil {
-				log.Printf("[ERROR] Failed to delete corrupt worktree %s: %v", wt.Name, err)
+			if err := p.deleteWorktree(repo, wt); err != nil {
+				p.log.Error("Failed to delete corrupt worktree", "op", "reconcile", "repo", repo.Name, "worktree", wt.Name, "error", err)
 			} else {
-				p.store.DeleteWorktree(wt.ID.String())
 				run.Cleaned++
 			}
 		}
 	}
 
-	// Create new worktrees if under capacity
+	// Create new worktrees if under capacity, or remove idle ones if over
 	currentCount := len(worktrees) - run.Cleaned
 	targetCount := repo.MaxWorktrees
 
+	if currentCount > targetCount {
+		run.Cleaned += p.shrinkPool(repo, worktrees, currentCount-targetCount)
+	}
+
 	if currentCount < targetCount {
 		toCreate := targetCount - currentCount
 
 		for i := 0; i < toCreate; i++ {
 			if err := p.createWorktree(repo); err != nil {
-				log.Printf("[ERROR] Failed to create worktree: %v", err)
+				p.log.Error("Failed to create worktree", "op", "reconcile", "repo", repo.Name, "error", err)
 			} else {
 				run.Created++
 			}
@@ -270,14 +604,14 @@ func (p *Pool) ReconcileWorktrees(repo *models.Repository) (*models.ReconcilerRu
 
 	// Fetch updates for repository
 	if err := p.allocator.FetchRepository(repo); err != nil {
-		log.Printf("[ERROR] Failed to fetch repository updates: %v", err)
+		p.log.Error("Failed to fetch repository updates", "op", "reconcile", "repo", repo.Name, "error", err)
 	}
 
 	// Update idle worktrees
 	idleWorktrees, _ := p.store.ListIdleWorktreesByRepo(repo.ID)
 	for _, wt := range idleWorktrees {
 		if err := p.allocator.UpdateWorktree(repo, wt); err != nil {
-			log.Printf("[ERROR] Failed to update worktree %s: %v", wt.Name, err)
+			p.log.Error("Failed to update worktree", "op", "reconcile", "repo", repo.Name, "worktree", wt.Name, "error", err)
 		}
 	}
 
@@ -287,7 +621,9 @@ func (p *Pool) ReconcileWorktrees(repo *models.Repository) (*models.ReconcilerRu
 // MaintainWorktreePool only manages pool size and cleans corrupt worktrees
 // It does NOT fetch updates - that's done via explicit refresh command
 func (p *Pool) MaintainWorktreePool(repo *models.Repository) (*models.ReconcilerRun, error) {
-	p.mu.Lock()
+	if err := p.mu.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock pool: %w", err)
+	}
 	defer p.mu.Unlock()
 
 	run := &models.ReconcilerRun{
@@ -306,25 +642,28 @@ func (p *Pool) MaintainWorktreePool(repo *models.Repository) (*models.Reconciler
 	// Clean up corrupt worktrees
 	for _, wt := range worktrees {
 		if wt.Status == models.WorktreeStatusCorrupt {
-			if err := p.allocator.DeleteWorktree(repo, wt); err != nil {
-				log.Printf("[ERROR] Failed to delete corrupt worktree %s: %v", wt.Name, err)
+			if err := p.deleteWorktree(repo, wt); err != nil {
+				p.log.Error("Failed to delete corrupt worktree", "op", "reconcile", "repo", repo.Name, "worktree", wt.Name, "error", err)
 			} else {
-				p.store.DeleteWorktree(wt.ID.String())
 				run.Cleaned++
 			}
 		}
 	}
 
-	// Create new worktrees if under capacity
+	// Create new worktrees if under capacity, or remove idle ones if over
 	currentCount := len(worktrees) - run.Cleaned
 	targetCount := repo.MaxWorktrees
 
+	if currentCount > targetCount {
+		run.Cleaned += p.shrinkPool(repo, worktrees, currentCount-targetCount)
+	}
+
 	if currentCount < targetCount {
 		toCreate := targetCount - currentCount
 
 		for i := 0; i < toCreate; i++ {
 			if err := p.createWorktree(repo); err != nil {
-				log.Printf("[ERROR] Failed to create worktree: %v", err)
+				p.log.Error("Failed to create worktree", "op", "reconcile", "repo", repo.Name, "error", err)
 			} else {
 				run.Created++
 			}
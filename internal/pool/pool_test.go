@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// git runs a git command in dir, failing the test if it fails
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.email=test@example.com", "-c", "user.name=Test"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// newTestPool returns a pool in a temporary directory with one tracked
+// repository of up to maxWorktrees worktrees on main, which is its own
+// origin
+func newTestPool(t *testing.T, maxWorktrees int) (*Pool, *models.Repository) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	repoPath := filepath.Join(dir, "repo")
+	git(t, dir, "init", "-q", "-b", "main", repoPath)
+	git(t, repoPath, "commit", "-q", "--allow-empty", "-m", "init")
+	git(t, repoPath, "remote", "add", "origin", repoPath)
+	git(t, repoPath, "fetch", "-q", "origin")
+
+	dataDir := filepath.Join(dir, "data")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.NewStoreWithPath(dataDir)
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	repo := models.NewRepository("app", repoPath, "main", maxWorktrees, 0)
+	if err := store.CreateRepository(repo); err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	return NewPool(store, nil, dataDir, lockfile.NewMutex(LockFile(dataDir))), repo
+}
+
+func TestClaimAtCapacityRecordsRejection(t *testing.T) {
+	p, repo := newTestPool(t, 1)
+
+	if _, err := p.ClaimWorktree(repo.Name, "first", ClaimOptions{OwnerUID: 1000}); err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+	_, err := p.ClaimWorktree(repo.Name, "second", ClaimOptions{Owner: "ci", OwnerUID: 1000})
+	if errcode.Of(err) != errcode.PoolAtCapacity {
+		t.Fatalf("ClaimWorktree() of a full pool error = %v, want pool_at_capacity", err)
+	}
+
+	claims, err := p.store.ListClaims(models.ClaimFilter{Branch: "second", Rejected: true})
+	if err != nil {
+		t.Fatalf("ListClaims() error = %v", err)
+	}
+	if len(claims) != 1 || claims[0].Outcome != models.ClaimOutcomeRejected || claims[0].Owner != "ci" {
+		t.Fatalf("claims of the rejected branch = %+v, want one rejected claim by ci", claims)
+	}
+
+	// Left out of the history unless asked for
+	if claims, err := p.store.ListClaims(models.ClaimFilter{}); err != nil || len(claims) != 1 || claims[0].Branch != "first" {
+		t.Errorf("ListClaims() = %+v, %v, want only the claim of first", claims, err)
+	}
+}
+
+// statuses returns the status of each worktree of a repository by name
+func statuses(t *testing.T, p *Pool, repo *models.Repository) map[string]models.WorktreeStatus {
+	t.Helper()
+	worktrees, err := p.store.ListWorktreesByRepo(repo.ID)
+	if err != nil {
+		t.Fatalf("ListWorktreesByRepo() error = %v", err)
+	}
+	byName := make(map[string]models.WorktreeStatus)
+	for _, wt := range worktrees {
+		byName[wt.Name] = wt.Status
+	}
+	return byName
+}
+
+func TestShrinkPoolDeletesIdleWorktreesFirst(t *testing.T) {
+	p, repo := newTestPool(t, 3)
+	if err := p.CreateInitialWorktrees(repo, 3); err != nil {
+		t.Fatalf("CreateInitialWorktrees() error = %v", err)
+	}
+	claimed, err := p.ClaimWorktree(repo.Name, "work", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+
+	repo.MaxWorktrees = 1
+	run, err := p.MaintainWorktreePool(repo)
+	if err != nil {
+		t.Fatalf("MaintainWorktreePool() error = %v", err)
+	}
+	if run.Cleaned != 2 || run.Created != 0 {
+		t.Errorf("MaintainWorktreePool() cleaned %d and created %d, want 2 and 0", run.Cleaned, run.Created)
+	}
+
+	got := statuses(t, p, repo)
+	if len(got) != 1 || got[claimed.Name] != models.WorktreeStatusInUse {
+		t.Errorf("worktrees after shrinking = %v, want only %s in use", got, claimed.Name)
+	}
+	if _, err := os.Stat(claimed.Path); err != nil {
+		t.Errorf("claimed worktree removed from disk: %v", err)
+	}
+}
+
+func TestShrinkPoolLeavesClaimedWorktrees(t *testing.T) {
+	p, repo := newTestPool(t, 2)
+	first, err := p.ClaimWorktree(repo.Name, "first", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+	second, err := p.ClaimWorktree(repo.Name, "second", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+
+	// Every worktree is claimed, so there is nothing to remove yet
+	repo.MaxWorktrees = 1
+	run, err := p.MaintainWorktreePool(repo)
+	if err != nil {
+		t.Fatalf("MaintainWorktreePool() error = %v", err)
+	}
+	got := statuses(t, p, repo)
+	if run.Cleaned != 0 || len(got) != 2 || got[first.Name] != models.WorktreeStatusInUse ||
+		got[second.Name] != models.WorktreeStatusInUse {
+		t.Fatalf("MaintainWorktreePool() cleaned %d, worktrees = %v, want both still in use", run.Cleaned, got)
+	}
+
+	// Once released, the excess worktree goes
+	if err := p.ReleaseWorktree(first.Name, ReleaseOptions{}); err != nil {
+		t.Fatalf("ReleaseWorktree() error = %v", err)
+	}
+	if _, err := p.MaintainWorktreePool(repo); err != nil {
+		t.Fatalf("MaintainWorktreePool() error = %v", err)
+	}
+	got = statuses(t, p, repo)
+	if len(got) != 1 || got[second.Name] != models.WorktreeStatusInUse {
+		t.Errorf("worktrees after release = %v, want only %s in use", got, second.Name)
+	}
+}
+
+func TestRebaseIdleWorktrees(t *testing.T) {
+	p, repo := newTestPool(t, 2)
+	if err := p.CreateInitialWorktrees(repo, 1); err != nil {
+		t.Fatalf("CreateInitialWorktrees() error = %v", err)
+	}
+	claimed, err := p.ClaimWorktree(repo.Name, "work", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+	if err := p.CreateInitialWorktrees(repo, 1); err != nil {
+		t.Fatalf("CreateInitialWorktrees() error = %v", err)
+	}
+	claimedHead := git(t, claimed.Path, "rev-parse", "HEAD")
+
+	git(t, repo.Path, "checkout", "-q", "-b", "develop")
+	git(t, repo.Path, "commit", "-q", "--allow-empty", "-m", "develop")
+	develop := git(t, repo.Path, "rev-parse", "HEAD")
+
+	repo.BaseBranch = "develop"
+	if err := p.RebaseIdleWorktrees(repo); err != nil {
+		t.Fatalf("RebaseIdleWorktrees() error = %v", err)
+	}
+
+	idle, err := p.store.ListIdleWorktreesByRepo(repo.ID)
+	if err != nil {
+		t.Fatalf("ListIdleWorktreesByRepo() error = %v", err)
+	}
+	if len(idle) != 1 {
+		t.Fatalf("got %d idle worktrees, want 1", len(idle))
+	}
+	if head := git(t, idle[0].Path, "rev-parse", "HEAD"); head != develop {
+		t.Errorf("idle worktree HEAD = %s, want the tip of develop %s", head, develop)
+	}
+	if head := git(t, claimed.Path, "rev-parse", "HEAD"); head != claimedHead {
+		t.Errorf("claimed worktree HEAD moved from %s to %s", claimedHead, head)
+	}
+}
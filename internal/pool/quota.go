@@ -0,0 +1,165 @@
+package pool
+
+import (
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// QuotaLimits caps concurrent claims. Zero means unlimited.
+type QuotaLimits struct {
+	// PerUser caps the claims of one uid
+	PerUser int
+	// PerLabel caps the claims made with one owner label; claims without a
+	// label aren't counted
+	PerLabel int
+}
+
+// Quotas are the limits ClaimWorktree enforces. The global limits count
+// claims in all repositories, the limits in Repos claims in one repository.
+// Both apply.
+type Quotas struct {
+	QuotaLimits
+	// Repos is keyed by repository name, compared case-insensitively
+	Repos map[string]QuotaLimits
+}
+
+// SetQuotas replaces the quotas enforced by later claims. Claims already
+// held are kept even if they exceed the new limits.
+func (p *Pool) SetQuotas(q Quotas) {
+	p.quotas.Store(&q)
+}
+
+// repoLimits returns the limits of a repository, if it has any
+func (q *Quotas) repoLimits(repo string) (QuotaLimits, bool) {
+	for name, limits := range q.Repos {
+		if strings.EqualFold(name, repo) {
+			return limits, true
+		}
+	}
+	return QuotaLimits{}, false
+}
+
+// checkQuotas fails if one more claim in repo by uid with label would exceed
+// a quota, given the claims held now
+func checkQuotas(q *Quotas, held []models.HeldClaim, repo string, uid int, label string) error {
+	check := func(limits QuotaLimits, inRepo string) error {
+		var byUser, byLabel int
+		for _, h := range held {
+			if inRepo != "" && h.RepoName != inRepo {
+				continue
+			}
+			if h.OwnerUID != nil && *h.OwnerUID == uid {
+				byUser++
+			}
+			if label != "" && h.Owner == label {
+				byLabel++
+			}
+		}
+
+		where := "in total"
+		if inRepo != "" {
+			where = "in repository '" + inRepo + "'"
+		}
+		if limits.PerUser > 0 && byUser >= limits.PerUser {
+			return errcode.New(errcode.QuotaExceeded, "quota exceeded: user %s already holds %d of %d claims %s",
+				userName(uid), byUser, limits.PerUser, where)
+		}
+		if limits.PerLabel > 0 && label != "" && byLabel >= limits.PerLabel {
+			return errcode.New(errcode.QuotaExceeded, "quota exceeded: owner '%s' already holds %d of %d claims %s",
+				label, byLabel, limits.PerLabel, where)
+		}
+		return nil
+	}
+
+	if err := check(q.QuotaLimits, ""); err != nil {
+		return err
+	}
+	if limits, ok := q.repoLimits(repo); ok {
+		return check(limits, repo)
+	}
+	return nil
+}
+
+// QuotaUsage reports the claims held against every quota by each user and
+// owner label holding any
+func (p *Pool) QuotaUsage() ([]models.QuotaUsage, error) {
+	q := p.quotas.Load()
+	if q == nil {
+		return []models.QuotaUsage{}, nil
+	}
+
+	held, err := p.store.ListHeldClaims()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := []models.QuotaUsage{}
+	add := func(limits QuotaLimits, inRepo string) {
+		// Report the repository's own name rather than the config key
+		for _, h := range held {
+			if strings.EqualFold(h.RepoName, inRepo) {
+				inRepo = h.RepoName
+				break
+			}
+		}
+
+		users := make(map[int]int)
+		labels := make(map[string]int)
+		for _, h := range held {
+			if inRepo != "" && h.RepoName != inRepo {
+				continue
+			}
+			if h.OwnerUID != nil {
+				users[*h.OwnerUID]++
+			}
+			if h.Owner != "" {
+				labels[h.Owner]++
+			}
+		}
+
+		if limits.PerUser > 0 {
+			for uid, used := range users {
+				uid := uid
+				usage = append(usage, models.QuotaUsage{Scope: "user", UID: &uid, Repo: inRepo, Used: used, Limit: limits.PerUser})
+			}
+		}
+		if limits.PerLabel > 0 {
+			for label, used := range labels {
+				usage = append(usage, models.QuotaUsage{Scope: "label", Label: label, Repo: inRepo, Used: used, Limit: limits.PerLabel})
+			}
+		}
+	}
+
+	add(q.QuotaLimits, "")
+	for repo, limits := range q.Repos {
+		add(limits, repo)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Scope != b.Scope {
+			return a.Scope > b.Scope // users first
+		}
+		if a.UID != nil && b.UID != nil {
+			return *a.UID < *b.UID
+		}
+		return a.Label < b.Label
+	})
+	return usage, nil
+}
+
+// userName returns the login name of a user, or the uid if it has none
+func userName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	alice, bob := 1001, 1002
+	held := []models.HeldClaim{
+		{RepoName: "web", OwnerUID: &alice, Owner: "swarm"},
+		{RepoName: "web", OwnerUID: &alice, Owner: "swarm"},
+		{RepoName: "api", OwnerUID: &alice, Owner: "ci"},
+		{RepoName: "api", OwnerUID: &bob, Owner: "ci"},
+		{RepoName: "api"}, // claimed before owners were recorded
+	}
+
+	tests := []struct {
+		name   string
+		quotas Quotas
+		repo   string
+		uid    int
+		label  string
+		denied bool
+	}{
+		{"no limits", Quotas{}, "web", alice, "swarm", false},
+		{"user at global limit", Quotas{QuotaLimits: QuotaLimits{PerUser: 3}}, "api", alice, "", true},
+		{"other user under global limit", Quotas{QuotaLimits: QuotaLimits{PerUser: 3}}, "api", bob, "", false},
+		{"label at global limit", Quotas{QuotaLimits: QuotaLimits{PerLabel: 2}}, "api", bob, "swarm", true},
+		{"unlabeled claim", Quotas{QuotaLimits: QuotaLimits{PerLabel: 1}}, "api", bob, "", false},
+		{"user at repo limit", Quotas{Repos: map[string]QuotaLimits{"web": {PerUser: 2}}}, "web", alice, "", true},
+		{"user under limit of other repo", Quotas{Repos: map[string]QuotaLimits{"web": {PerUser: 2}}}, "api", alice, "", false},
+		{"repo names ignore case", Quotas{Repos: map[string]QuotaLimits{"WEB": {PerLabel: 2}}}, "web", bob, "swarm", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkQuotas(&tt.quotas, held, tt.repo, tt.uid, tt.label)
+			if tt.denied && errcode.Of(err) != errcode.QuotaExceeded {
+				t.Errorf("checkQuotas() error = %v, want quota_exceeded", err)
+			}
+			if !tt.denied && err != nil {
+				t.Errorf("checkQuotas() error = %v, want nil", err)
+			}
+		})
+	}
+}
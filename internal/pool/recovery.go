@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"fmt"
+
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// Recover finishes or undoes worktree operations that were interrupted, e.g.
+// by a crash, using the transitional status persisted before their git work
+// began. Since every operation holds the pool lock, across processes, until
+// it is done, it is safe to run at any time. It returns how many worktrees it
+// recovered.
+//
+//   - creating: rolled back, the worktree was never handed out
+//   - claiming: rolled back to idle, the claimant never got an answer
+//   - releasing: rolled forward to idle, the claimant already gave it up
+//   - deleting: rolled forward, the worktree is removed
+func (p *Pool) Recover() (int, error) {
+	if err := p.mu.Lock(); err != nil {
+		return 0, fmt.Errorf("failed to lock pool: %w", err)
+	}
+	defer p.mu.Unlock()
+
+	repos, err := p.store.ListRepositories()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	recovered := 0
+	for _, repo := range repos {
+		worktrees, err := p.store.ListWorktreesByRepo(repo.ID)
+		if err != nil {
+			return recovered, fmt.Errorf("failed to list worktrees: %w", err)
+		}
+
+		for _, wt := range worktrees {
+			if !wt.Status.Transitional() {
+				continue
+			}
+			p.recoverWorktree(repo, wt)
+			recovered++
+		}
+	}
+
+	return recovered, nil
+}
+
+func (p *Pool) recoverWorktree(repo *models.Repository, wt *models.Worktree) {
+	var branch string
+	if wt.Branch != nil {
+		branch = *wt.Branch
+	}
+	log := p.log.With("op", "recover", "repo", repo.Name, "worktree", wt.Name, "status", string(wt.Status), "branch", branch)
+
+	switch wt.Status {
+	case models.WorktreeStatusCreating:
+		if p.discardWorktree(repo, wt) {
+			log.Warn("Rolled back interrupted create")
+		}
+
+	case models.WorktreeStatusClaiming:
+		if err := p.allocator.ResetWorktree(repo, wt); err != nil {
+			p.markCorrupt(wt, models.WorktreeStatusClaiming)
+			log.Warn("Failed to roll back interrupted claim, marked corrupt", "error", err)
+			return
+		}
+		if err := p.store.TransitionWorktree(wt.ID.String(), models.WorktreeStatusClaiming,
+			models.WorktreeStatusIdle, nil, nil); err != nil {
+			log.Error("Failed to update worktree status", "error", err)
+			return
+		}
+		log.Warn("Rolled back interrupted claim")
+
+	case models.WorktreeStatusReleasing:
+		if err := p.allocator.ResetWorktree(repo, wt); err != nil {
+			p.markCorrupt(wt, models.WorktreeStatusReleasing)
+			p.finishClaim(wt, nil, models.ClaimOutcomeCorrupt)
+			p.events.Publish(events.Event{
+				Type:       events.EventWorktreeCorrupted,
+				Repo:       repo.Name,
+				WorktreeID: wt.Name,
+				Branch:     branch,
+			})
+			log.Warn("Failed to finish interrupted release, marked corrupt", "error", err)
+			return
+		}
+		if err := p.store.TransitionWorktree(wt.ID.String(), models.WorktreeStatusReleasing,
+			models.WorktreeStatusIdle, nil, nil); err != nil {
+			log.Error("Failed to update worktree status", "error", err)
+			return
+		}
+		p.finishClaim(wt, nil, models.ClaimOutcomeReleased)
+		p.events.Publish(events.Event{
+			Type:       events.EventWorktreeReleased,
+			Repo:       repo.Name,
+			WorktreeID: wt.Name,
+			Branch:     branch,
+		})
+		log.Warn("Finished interrupted release")
+
+	case models.WorktreeStatusDeleting:
+		if p.discardWorktree(repo, wt) {
+			p.events.Publish(events.Event{
+				Type:       events.EventWorktreeDeleted,
+				Repo:       repo.Name,
+				WorktreeID: wt.Name,
+			})
+			log.Warn("Finished interrupted delete")
+		}
+	}
+}
+
+// discardWorktree removes whatever exists of a worktree on disk and its
+// database row. If the directory can't be removed the worktree is marked
+// corrupt instead, so the reconciler retries.
+func (p *Pool) discardWorktree(repo *models.Repository, wt *models.Worktree) bool {
+	if err := p.allocator.DeleteWorktree(repo, wt); err != nil {
+		p.log.Error("Failed to remove worktree", "op", "delete", "repo", repo.Name, "worktree", wt.Name, "error", err)
+		p.markCorrupt(wt, wt.Status)
+		return false
+	}
+
+	if err := p.store.DeleteWorktree(wt.ID.String()); err != nil {
+		p.log.Error("Failed to delete worktree record", "op", "delete", "repo", repo.Name, "worktree", wt.Name, "error", err)
+		return false
+	}
+	return true
+}
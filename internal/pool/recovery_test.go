@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// TestRecover interrupts each operation by leaving a worktree in its
+// transitional status and checks that recovery rolls it forward or back
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name string
+		// interrupt leaves a worktree of a pool of one mid-operation and
+		// returns it
+		interrupt func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree
+		// status is the worktree's status afterwards, empty if it is gone
+		status  models.WorktreeStatus
+		outcome models.ClaimOutcome
+	}{
+		{
+			name: "creating is rolled back",
+			interrupt: func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+				return transition(t, p, idleWorktree(t, p, repo), models.WorktreeStatusCreating, nil)
+			},
+		},
+		{
+			name: "claiming is rolled back",
+			interrupt: func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+				branch := "work"
+				return transition(t, p, idleWorktree(t, p, repo), models.WorktreeStatusClaiming, &branch)
+			},
+			status: models.WorktreeStatusIdle,
+		},
+		{
+			name: "releasing is rolled forward",
+			interrupt: func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+				wt := claimedWorktree(t, p, repo)
+				if err := os.WriteFile(filepath.Join(wt.Path, "scratch.txt"), []byte("left over"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return transition(t, p, wt, models.WorktreeStatusReleasing, wt.Branch)
+			},
+			status:  models.WorktreeStatusIdle,
+			outcome: models.ClaimOutcomeReleased,
+		},
+		{
+			name: "releasing a broken worktree marks it corrupt",
+			interrupt: func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+				wt := claimedWorktree(t, p, repo)
+				if err := os.RemoveAll(wt.Path); err != nil {
+					t.Fatal(err)
+				}
+				return transition(t, p, wt, models.WorktreeStatusReleasing, wt.Branch)
+			},
+			status:  models.WorktreeStatusCorrupt,
+			outcome: models.ClaimOutcomeCorrupt,
+		},
+		{
+			name: "deleting is rolled forward",
+			interrupt: func(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+				return transition(t, p, idleWorktree(t, p, repo), models.WorktreeStatusDeleting, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, repo := newTestPool(t, 1)
+			wt := tt.interrupt(t, p, repo)
+
+			recovered, err := p.Recover()
+			if err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+			if recovered != 1 {
+				t.Errorf("Recover() = %d, want 1", recovered)
+			}
+
+			got := statuses(t, p, repo)
+			if tt.status == "" {
+				if len(got) != 0 {
+					t.Errorf("worktrees after recovery = %v, want none", got)
+				}
+				if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+					t.Errorf("worktree directory still exists: %v", err)
+				}
+			} else if got[wt.Name] != tt.status {
+				t.Errorf("status after recovery = %q, want %q", got[wt.Name], tt.status)
+			}
+			if tt.status == models.WorktreeStatusIdle {
+				if _, err := os.Stat(filepath.Join(wt.Path, "scratch.txt")); !os.IsNotExist(err) {
+					t.Errorf("left over file still exists after recovery: %v", err)
+				}
+			}
+
+			if tt.outcome != "" {
+				claims, err := p.store.ListClaims(models.ClaimFilter{RepoName: repo.Name})
+				if err != nil {
+					t.Fatalf("ListClaims() error = %v", err)
+				}
+				if len(claims) != 1 || claims[0].Outcome != tt.outcome || claims[0].ReleasedAt == nil {
+					t.Fatalf("claims after recovery = %+v, want one finished with %q", claims, tt.outcome)
+				}
+			}
+
+			// Nothing is left to recover
+			if recovered, err := p.Recover(); err != nil || recovered != 0 {
+				t.Errorf("second Recover() = %d, %v, want 0", recovered, err)
+			}
+		})
+	}
+}
+
+// idleWorktree adds an idle worktree to the pool
+func idleWorktree(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+	t.Helper()
+	if err := p.CreateInitialWorktrees(repo, 1); err != nil {
+		t.Fatalf("CreateInitialWorktrees() error = %v", err)
+	}
+	idle, err := p.store.ListIdleWorktreesByRepo(repo.ID)
+	if err != nil || len(idle) != 1 {
+		t.Fatalf("ListIdleWorktreesByRepo() = %v, %v, want one worktree", idle, err)
+	}
+	return idle[0]
+}
+
+// claimedWorktree claims a worktree of the pool
+func claimedWorktree(t *testing.T, p *Pool, repo *models.Repository) *models.Worktree {
+	t.Helper()
+	wt, err := p.ClaimWorktree(repo.Name, "work", ClaimOptions{})
+	if err != nil {
+		t.Fatalf("ClaimWorktree() error = %v", err)
+	}
+	return wt
+}
+
+// transition moves a worktree to a transitional status, as the operation
+// does before its git work begins
+func transition(t *testing.T, p *Pool, wt *models.Worktree, to models.WorktreeStatus, branch *string) *models.Worktree {
+	t.Helper()
+	var leasedAt *time.Time
+	if branch != nil {
+		now := time.Now()
+		leasedAt = &now
+	}
+	if err := p.store.TransitionWorktree(wt.ID.String(), wt.Status, to, leasedAt, branch); err != nil {
+		t.Fatalf("TransitionWorktree() error = %v", err)
+	}
+	wt.Status = to
+	return wt
+}
@@ -2,55 +2,78 @@ package repo
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/albertywu/gitpool/internal/config"
 	"github.com/albertywu/gitpool/internal/db"
+	"github.com/albertywu/gitpool/internal/errcode"
+	"github.com/albertywu/gitpool/internal/events"
+	"github.com/albertywu/gitpool/internal/lockfile"
+	"github.com/albertywu/gitpool/internal/logging"
 	"github.com/albertywu/gitpool/internal/models"
 )
 
 type Manager struct {
 	store     *db.Store
 	validator *Validator
+	events    *events.Bus
+	log       *slog.Logger
+	// poolLock is the pool's lock, held while removing worktrees
+	poolLock *lockfile.Mutex
 }
 
-func NewManager(store *db.Store) *Manager {
+func NewManager(store *db.Store, bus *events.Bus, poolLock *lockfile.Mutex) *Manager {
 	return &Manager{
 		store:     store,
 		validator: NewValidator(),
+		events:    bus,
+		log:       logging.Component("repo"),
+		poolLock:  poolLock,
 	}
 }
 
+// AddRepository tracks the repository at path. A maxWorktrees of zero uses
+// the default pool size.
 func (m *Manager) AddRepository(name, path, baseBranch string, maxWorktrees int) (*models.Repository, error) {
+	if maxWorktrees == 0 {
+		maxWorktrees = config.DefaultMaxWorktrees
+	}
+	if maxWorktrees < 1 {
+		return nil, errcode.New(errcode.InvalidArgument, "max worktrees must be at least 1")
+	}
+
 	// Validate repository path
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve path: %w", err)
+		return nil, errcode.Wrap(errcode.InvalidArgument, err, "failed to resolve path")
 	}
 
 	if err := m.validator.ValidateRepository(absPath); err != nil {
-		return nil, fmt.Errorf("repository validation failed: %w", err)
+		return nil, errcode.Wrap(errcode.InvalidArgument, err, "repository validation failed")
 	}
 
 	// Auto-detect base branch if not provided
 	if baseBranch == "" {
 		detected, err := m.validator.GetDefaultBranch(absPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to detect base branch: %w", err)
+			return nil, errcode.Wrap(errcode.InvalidArgument, err, "failed to detect base branch")
 		}
 		baseBranch = detected
-		log.Printf("[INFO] Auto-detected base branch: %s", baseBranch)
+		m.log.Info("Auto-detected base branch", "op", "track", "repo", name, "base_branch", baseBranch)
 	}
 
 	// Validate base branch
 	if err := m.validator.ValidateBranch(absPath, baseBranch); err != nil {
-		return nil, fmt.Errorf("branch validation failed: %w", err)
+		return nil, errcode.Wrap(errcode.InvalidArgument, err, "branch validation failed")
 	}
 
 	// Check if repository already exists
 	if _, err := m.store.GetRepository(name); err == nil {
-		return nil, fmt.Errorf("repository '%s' already exists", name)
+		return nil, errcode.New(errcode.RepoExists, "repository '%s' already exists", name)
 	}
 
 	// Create repository record - no fetch interval, refresh is manual
@@ -59,9 +82,75 @@ func (m *Manager) AddRepository(name, path, baseBranch string, maxWorktrees int)
 		return nil, fmt.Errorf("failed to save repository: %w", err)
 	}
 
-	log.Printf("[INFO] Added repo '%s' at %s", name, absPath)
-	log.Printf("[INFO] Max worktrees: %d, Base branch: %s",
-		maxWorktrees, baseBranch)
+	m.log.Info("Added repo", "op", "track", "repo", name, "path", absPath,
+		"max_worktrees", maxWorktrees, "base_branch", baseBranch)
+
+	m.events.Publish(events.Event{
+		Type: events.EventRepoTracked,
+		Repo: name,
+		Data: map[string]interface{}{
+			"path":          absPath,
+			"base_branch":   baseBranch,
+			"max_worktrees": maxWorktrees,
+		},
+	})
+
+	return repo, nil
+}
+
+// RepoUpdate holds the settings to change on a tracked repository; nil
+// fields are left as they are
+type RepoUpdate struct {
+	MaxWorktrees *int
+	BaseBranch   *string
+	// FetchInterval is in minutes; zero turns scheduled fetches off
+	FetchInterval *int
+}
+
+// UpdateRepository changes a tracked repository's settings in place. The
+// reconciler brings the pool in line with them.
+func (m *Manager) UpdateRepository(name string, update RepoUpdate) (*models.Repository, error) {
+	repo, err := m.store.GetRepository(name)
+	if err != nil {
+		return nil, errcode.New(errcode.RepoNotFound, "repository '%s' not found", name)
+	}
+
+	data := map[string]interface{}{}
+	if update.MaxWorktrees != nil && *update.MaxWorktrees != repo.MaxWorktrees {
+		if *update.MaxWorktrees < 1 {
+			return nil, errcode.New(errcode.InvalidArgument, "max worktrees must be at least 1")
+		}
+		data["max_worktrees"] = *update.MaxWorktrees
+		repo.MaxWorktrees = *update.MaxWorktrees
+	}
+	if update.BaseBranch != nil && *update.BaseBranch != repo.BaseBranch {
+		if err := m.validator.ValidateBranch(repo.Path, *update.BaseBranch); err != nil {
+			return nil, errcode.Wrap(errcode.InvalidArgument, err, "branch validation failed")
+		}
+		data["base_branch"] = *update.BaseBranch
+		repo.BaseBranch = *update.BaseBranch
+	}
+
+	if update.FetchInterval != nil && *update.FetchInterval != repo.FetchInterval {
+		if *update.FetchInterval < 0 {
+			return nil, errcode.New(errcode.InvalidArgument, "fetch interval must not be negative")
+		}
+		data["fetch_interval_minutes"] = *update.FetchInterval
+		repo.FetchInterval = *update.FetchInterval
+	}
+
+	if len(data) == 0 {
+		return repo, nil
+	}
+
+	if err := m.store.UpdateRepository(repo); err != nil {
+		return nil, fmt.Errorf("failed to update repository: %w", err)
+	}
+
+	m.log.Info("Updated repo", "op", "update", "repo", name,
+		"max_worktrees", repo.MaxWorktrees, "base_branch", repo.BaseBranch, "fetch_interval_minutes", repo.FetchInterval)
+
+	m.events.Publish(events.Event{Type: events.EventRepoUpdated, Repo: name, Data: data})
 
 	return repo, nil
 }
@@ -71,53 +160,78 @@ func (m *Manager) ListRepositories() ([]*models.Repository, error) {
 }
 
 func (m *Manager) RemoveRepository(name string) error {
+	// Keep pool operations, also of other processes, away from the worktrees
+	// while they are deleted
+	if err := m.poolLock.Lock(); err != nil {
+		return fmt.Errorf("failed to lock pool: %w", err)
+	}
+	defer m.poolLock.Unlock()
+
 	// Get repository
 	repo, err := m.store.GetRepository(name)
 	if err != nil {
-		return fmt.Errorf("repository '%s' not found", name)
+		return errcode.New(errcode.RepoNotFound, "repository '%s' not found", name)
 	}
 
-	// Get all worktrees for this repository
-	worktrees, err := m.store.ListWorktreesByRepo(repo.ID)
-	if err != nil {
-		return fmt.Errorf("failed to list worktrees: %w", err)
-	}
+	// Check for claims and delete the records in one transaction, so a claim
+	// can't slip in between
+	var worktrees []*models.Worktree
+	err = m.store.WithTx(func(tx *db.Store) error {
+		var err error
+		worktrees, err = tx.ListWorktreesByRepo(repo.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list worktrees: %w", err)
+		}
 
-	// Count in-use worktrees
-	inUseCount := 0
-	for _, wt := range worktrees {
-		if wt.Status == models.WorktreeStatusInUse {
-			inUseCount++
+		inUseCount := 0
+		for _, wt := range worktrees {
+			if wt.Status.HoldsBranch() {
+				inUseCount++
+			}
+		}
+		if inUseCount > 0 {
+			return errcode.New(errcode.WorktreeInUse, "cannot remove repository with %d worktrees in use", inUseCount)
 		}
-	}
 
-	if inUseCount > 0 {
-		return fmt.Errorf("cannot remove repository with %d worktrees in use", inUseCount)
+		if err := tx.DeleteWorktreesByRepo(repo.ID); err != nil {
+			return fmt.Errorf("failed to delete worktree records: %w", err)
+		}
+		if err := tx.DeleteRepository(name); err != nil {
+			return fmt.Errorf("failed to delete repository record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[WARN] Removing repo '%s'", name)
+	m.log.Warn("Removing repo", "op", "untrack", "repo", name)
 
 	// Delete worktree directories
 	deletedCount := 0
 	for _, wt := range worktrees {
-		if wt.Status != models.WorktreeStatusInUse {
-			if err := os.RemoveAll(wt.Path); err != nil {
-				log.Printf("[ERROR] Failed to delete worktree directory %s: %v", wt.Path, err)
-			} else {
-				deletedCount++
-			}
-			// Delete from database regardless
-			m.store.DeleteWorktree(wt.ID.String())
+		if err := os.RemoveAll(wt.Path); err != nil {
+			m.log.Error("Failed to delete worktree directory", "op", "untrack", "repo", name, "worktree", wt.Name, "path", wt.Path, "error", err)
+		} else {
+			deletedCount++
 		}
+		m.events.Publish(events.Event{
+			Type:       events.EventWorktreeDeleted,
+			Repo:       name,
+			WorktreeID: wt.Name,
+		})
 	}
 
-	// Delete repository record
-	if err := m.store.DeleteRepository(name); err != nil {
-		return fmt.Errorf("failed to delete repository record: %w", err)
+	// Drop the source repository's records of the deleted worktrees, which
+	// would otherwise keep their branches checked out
+	if output, err := exec.Command("git", "-C", repo.Path, "worktree", "prune").CombinedOutput(); err != nil {
+		m.log.Warn("Failed to prune worktrees of source repository", "op", "untrack", "repo", name, "path", repo.Path,
+			"error", err, "output", strings.TrimSpace(string(output)))
 	}
 
-	log.Printf("[INFO] Deleted %d idle worktrees", deletedCount)
-	log.Printf("[INFO] Repo '%s' removed successfully", name)
+	m.log.Info("Repo removed", "op", "untrack", "repo", name, "deleted_worktrees", deletedCount)
+
+	m.events.Publish(events.Event{Type: events.EventRepoUntracked, Repo: name})
 
 	return nil
 }
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// ActionKind says what 'gp apply' does to converge one repository
+type ActionKind string
+
+const (
+	ActionTrack   ActionKind = "track"
+	ActionUpdate  ActionKind = "update"
+	ActionUntrack ActionKind = "untrack"
+	// ActionConflict marks differences apply can't resolve in place
+	ActionConflict ActionKind = "conflict"
+)
+
+// Action is one step of an apply plan
+type Action struct {
+	Kind ActionKind
+	Name string
+	Spec config.RepoSpec
+	// Update holds the settings to change for ActionUpdate
+	Update RepoUpdate
+	// Changes describes the action for humans
+	Changes []string
+}
+
+// Plan compares declared repositories with tracked ones and returns the
+// actions that make them match, ordered by repository name. Repositories that
+// already match produce no action. Tracked repositories that aren't declared
+// get an untrack action; callers decide whether to prune them.
+func Plan(declared map[string]config.RepoSpec, tracked []*models.Repository) []Action {
+	var actions []Action
+
+	trackedByName := make(map[string]*models.Repository, len(tracked))
+	for _, repo := range tracked {
+		trackedByName[repo.Name] = repo
+		if _, ok := declared[repo.Name]; !ok {
+			actions = append(actions, Action{
+				Kind:    ActionUntrack,
+				Name:    repo.Name,
+				Changes: []string{"not declared in config"},
+			})
+		}
+	}
+
+	for name, spec := range declared {
+		repo, ok := trackedByName[name]
+		if !ok {
+			changes := []string{fmt.Sprintf("path %s", spec.Path), fmt.Sprintf("max_worktrees %d", spec.MaxWorktrees)}
+			if spec.BaseBranch != "" {
+				changes = append(changes, fmt.Sprintf("base_branch %s", spec.BaseBranch))
+			}
+			if spec.FetchInterval != 0 {
+				changes = append(changes, fmt.Sprintf("fetch_interval %s", fetchInterval(specMinutes(spec))))
+			}
+			actions = append(actions, Action{Kind: ActionTrack, Name: name, Spec: spec, Changes: changes})
+			continue
+		}
+
+		if filepath.Clean(repo.Path) != filepath.Clean(spec.Path) {
+			actions = append(actions, Action{
+				Kind: ActionConflict,
+				Name: name,
+				Spec: spec,
+				Changes: []string{fmt.Sprintf("tracked at %s but declared at %s; untrack it to move it",
+					repo.Path, spec.Path)},
+			})
+			continue
+		}
+
+		action := Action{Kind: ActionUpdate, Name: name, Spec: spec}
+		if spec.MaxWorktrees != repo.MaxWorktrees {
+			max := spec.MaxWorktrees
+			action.Update.MaxWorktrees = &max
+			action.Changes = append(action.Changes, fmt.Sprintf("max_worktrees %d -> %d", repo.MaxWorktrees, max))
+		}
+		// An undeclared base branch keeps whatever was detected on track
+		if spec.BaseBranch != "" && spec.BaseBranch != repo.BaseBranch {
+			branch := spec.BaseBranch
+			action.Update.BaseBranch = &branch
+			action.Changes = append(action.Changes, fmt.Sprintf("base_branch %s -> %s", repo.BaseBranch, branch))
+		}
+		if minutes := specMinutes(spec); minutes != repo.FetchInterval {
+			action.Update.FetchInterval = &minutes
+			action.Changes = append(action.Changes, fmt.Sprintf("fetch_interval %s -> %s",
+				fetchInterval(repo.FetchInterval), fetchInterval(minutes)))
+		}
+		if len(action.Changes) > 0 {
+			actions = append(actions, action)
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Name < actions[j].Name
+	})
+	return actions
+}
+
+// specMinutes returns the fetch interval of a declaration in minutes, as
+// repositories store it
+func specMinutes(spec config.RepoSpec) int {
+	return int(spec.FetchInterval / time.Minute)
+}
+
+// fetchInterval formats a fetch interval in minutes for plans
+func fetchInterval(minutes int) string {
+	switch {
+	case minutes == 0:
+		return "off"
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/config"
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+func TestPlan(t *testing.T) {
+	declared := map[string]config.RepoSpec{
+		"api":   {Path: "/src/api", MaxWorktrees: 4},
+		"web":   {Path: "/src/web", MaxWorktrees: 8, BaseBranch: "develop"},
+		"moved": {Path: "/new/moved", MaxWorktrees: 8},
+		"same":  {Path: "/src/same", MaxWorktrees: 8},
+		"fetch": {Path: "/src/fetch", MaxWorktrees: 8, FetchInterval: 90 * time.Minute},
+	}
+	tracked := []*models.Repository{
+		models.NewRepository("web", "/src/web", "main", 2, 0),
+		models.NewRepository("moved", "/old/moved", "main", 8, 0),
+		models.NewRepository("same", "/src/same", "main", 8, 0),
+		models.NewRepository("fetch", "/src/fetch", "main", 8, 60),
+		models.NewRepository("legacy", "/src/legacy", "main", 8, 0),
+	}
+
+	actions := Plan(declared, tracked)
+
+	want := []struct {
+		name string
+		kind ActionKind
+	}{
+		{"api", ActionTrack},
+		{"fetch", ActionUpdate},
+		{"legacy", ActionUntrack},
+		{"moved", ActionConflict},
+		{"web", ActionUpdate},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("got %d actions %+v, want %d", len(actions), actions, len(want))
+	}
+	for i, w := range want {
+		if actions[i].Name != w.name || actions[i].Kind != w.kind {
+			t.Errorf("action %d = %s %s, want %s %s", i, actions[i].Kind, actions[i].Name, w.kind, w.name)
+		}
+	}
+
+	fetch := actions[1]
+	if fetch.Update.FetchInterval == nil || *fetch.Update.FetchInterval != 90 {
+		t.Errorf("update fetch interval = %v, want 90", fetch.Update.FetchInterval)
+	}
+	if len(fetch.Changes) != 1 || fetch.Changes[0] != "fetch_interval 1h -> 90m" {
+		t.Errorf("update changes = %v, want fetch_interval 1h -> 90m", fetch.Changes)
+	}
+
+	update := actions[4].Update
+	if update.MaxWorktrees == nil || *update.MaxWorktrees != 8 {
+		t.Errorf("update max worktrees = %v, want 8", update.MaxWorktrees)
+	}
+	if update.BaseBranch == nil || *update.BaseBranch != "develop" {
+		t.Errorf("update base branch = %v, want develop", update.BaseBranch)
+	}
+}
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+// Report summarizes how a repository's pool was used over a time window
+type Report struct {
+	RepoName        string
+	Since           time.Time
+	Until           time.Time
+	MaxWorktrees    int
+	Claims          int
+	RejectedClaims  int // claims that failed because the pool was at capacity
+	PeakConcurrent  int
+	TimeAtCapacity  time.Duration
+	ClaimLatencyP50 time.Duration
+	ClaimLatencyP95 time.Duration
+	LeaseP50        time.Duration
+	CorruptRate     float64
+	RecommendedSize int
+}
+
+// Compute builds a usage report for one repository from its claim history.
+// Claims may start before since; only the part of each lease that falls
+// inside [since, until] counts towards concurrency and time at capacity.
+// Rejected claims are counted separately and, as demand for one more
+// worktree than was held when they were turned away, raise the recommended
+// size. Time at capacity only counts time every worktree was held.
+func Compute(repo *models.Repository, claims []*models.Claim, since, until time.Time) *Report {
+	report := &Report{
+		RepoName:     repo.Name,
+		Since:        since,
+		Until:        until,
+		MaxWorktrees: repo.MaxWorktrees,
+	}
+
+	type edge struct {
+		at    time.Time
+		delta int
+		// inWindow marks the start of a claim made inside the window
+		inWindow bool
+		// rejected marks a claim turned away, which holds nothing
+		rejected bool
+	}
+
+	var edges []edge
+	var latencies, leases []time.Duration
+	var finished, corrupt int
+
+	for _, c := range claims {
+		if c.Outcome == models.ClaimOutcomeRejected {
+			if !c.ClaimedAt.Before(since) && !c.ClaimedAt.After(until) {
+				report.RejectedClaims++
+				edges = append(edges, edge{at: c.ClaimedAt, inWindow: true, rejected: true})
+			}
+			continue
+		}
+
+		end := until
+		if c.ReleasedAt != nil && c.ReleasedAt.Before(until) {
+			end = *c.ReleasedAt
+		}
+		if end.Before(since) || c.ClaimedAt.After(until) {
+			continue
+		}
+
+		start := c.ClaimedAt
+		if start.Before(since) {
+			start = since
+		}
+		inWindow := !c.ClaimedAt.Before(since)
+		edges = append(edges, edge{at: start, delta: 1, inWindow: inWindow}, edge{at: end, delta: -1})
+
+		// Per-claim figures only count claims made inside the window
+		if !inWindow {
+			continue
+		}
+		report.Claims++
+
+		if c.LatencyMS != nil {
+			latencies = append(latencies, time.Duration(*c.LatencyMS)*time.Millisecond)
+		}
+
+		if c.ReleasedAt != nil {
+			leases = append(leases, c.ReleasedAt.Sub(c.ClaimedAt))
+		}
+
+		switch c.Outcome {
+		case models.ClaimOutcomeReleased:
+			finished++
+		case models.ClaimOutcomeCorrupt:
+			finished++
+			corrupt++
+		}
+	}
+
+	// Sweep the claim start/end edges in time order; releases sort before
+	// claims at the same instant so back-to-back leases don't overlap
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+
+	var concurrentAtClaim []int
+	current := 0
+	for i, e := range edges {
+		if i > 0 && repo.MaxWorktrees > 0 && current >= repo.MaxWorktrees {
+			report.TimeAtCapacity += e.at.Sub(edges[i-1].at)
+		}
+		current += e.delta
+		switch {
+		case e.rejected:
+			// Serving it would have taken one more worktree
+			concurrentAtClaim = append(concurrentAtClaim, current+1)
+		case e.inWindow:
+			concurrentAtClaim = append(concurrentAtClaim, current)
+		}
+		if current > report.PeakConcurrent {
+			report.PeakConcurrent = current
+		}
+	}
+
+	report.ClaimLatencyP50 = percentile(latencies, 0.50)
+	report.ClaimLatencyP95 = percentile(latencies, 0.95)
+	report.LeaseP50 = percentile(leases, 0.50)
+
+	if finished > 0 {
+		report.CorruptRate = float64(corrupt) / float64(finished)
+	}
+
+	report.RecommendedSize = recommendSize(concurrentAtClaim, repo.MaxWorktrees)
+
+	return report
+}
+
+// recommendSize picks a pool size that would have served 95% of claims,
+// rejected ones included, without waiting, plus one spare worktree. With no
+// usage data the current size is kept.
+func recommendSize(concurrentAtClaim []int, current int) int {
+	if len(concurrentAtClaim) == 0 {
+		return current
+	}
+
+	levels := make([]int, len(concurrentAtClaim))
+	copy(levels, concurrentAtClaim)
+	sort.Ints(levels)
+
+	idx := int(math.Ceil(0.95*float64(len(levels)))) - 1
+	return levels[idx] + 1
+}
+
+// percentile returns the nearest-rank percentile of the given durations
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
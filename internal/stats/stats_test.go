@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/albertywu/gitpool/internal/models"
+)
+
+func claimAt(start time.Time, lease time.Duration, latency time.Duration, outcome models.ClaimOutcome) *models.Claim {
+	ms := latency.Milliseconds()
+	c := &models.Claim{
+		ClaimedAt: start,
+		Outcome:   outcome,
+		LatencyMS: &ms,
+	}
+	if outcome != models.ClaimOutcomeActive {
+		released := start.Add(lease)
+		c.ReleasedAt = &released
+	}
+	return c
+}
+
+func TestCompute(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(10 * time.Hour)
+	repo := &models.Repository{Name: "my-app", MaxWorktrees: 2}
+
+	claims := []*models.Claim{
+		// Overlaps with the next two leases put the pool at capacity from 2h to 4h
+		claimAt(since.Add(1*time.Hour), 2*time.Hour, 100*time.Millisecond, models.ClaimOutcomeReleased),
+		claimAt(since.Add(2*time.Hour), 2*time.Hour, 300*time.Millisecond, models.ClaimOutcomeCorrupt),
+		// Back-to-back with the first lease ending at 3h - not overlapping
+		claimAt(since.Add(3*time.Hour), 1*time.Hour, 200*time.Millisecond, models.ClaimOutcomeReleased),
+		// Still active at the end of the window
+		claimAt(since.Add(9*time.Hour), 0, 400*time.Millisecond, models.ClaimOutcomeActive),
+	}
+
+	report := Compute(repo, claims, since, until)
+
+	if report.Claims != 4 {
+		t.Errorf("expected 4 claims, got %d", report.Claims)
+	}
+
+	if report.PeakConcurrent != 2 {
+		t.Errorf("expected peak of 2 concurrent claims, got %d", report.PeakConcurrent)
+	}
+
+	if report.TimeAtCapacity != 2*time.Hour {
+		t.Errorf("expected 2h at capacity, got %s", report.TimeAtCapacity)
+	}
+
+	if report.ClaimLatencyP50 != 200*time.Millisecond {
+		t.Errorf("expected p50 latency 200ms, got %s", report.ClaimLatencyP50)
+	}
+
+	if report.ClaimLatencyP95 != 400*time.Millisecond {
+		t.Errorf("expected p95 latency 400ms, got %s", report.ClaimLatencyP95)
+	}
+
+	if report.LeaseP50 != 2*time.Hour {
+		t.Errorf("expected median lease 2h, got %s", report.LeaseP50)
+	}
+
+	if report.CorruptRate < 0.33 || report.CorruptRate > 0.34 {
+		t.Errorf("expected corrupt rate 1/3, got %f", report.CorruptRate)
+	}
+
+	if report.RecommendedSize != 3 {
+		t.Errorf("expected recommended size 3, got %d", report.RecommendedSize)
+	}
+}
+
+func TestComputeClipsLeasesToWindow(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(4 * time.Hour)
+	repo := &models.Repository{Name: "my-app", MaxWorktrees: 1}
+
+	// Claimed before the window and released inside it
+	claims := []*models.Claim{
+		claimAt(since.Add(-1*time.Hour), 2*time.Hour, time.Second, models.ClaimOutcomeReleased),
+	}
+
+	report := Compute(repo, claims, since, until)
+
+	if report.Claims != 0 {
+		t.Errorf("expected claims made before the window to be excluded, got %d", report.Claims)
+	}
+
+	if report.TimeAtCapacity != time.Hour {
+		t.Errorf("expected 1h at capacity inside the window, got %s", report.TimeAtCapacity)
+	}
+
+	if report.RecommendedSize != 1 {
+		t.Errorf("expected current size to be kept without claims, got %d", report.RecommendedSize)
+	}
+}
+
+func TestComputeCountsRejectedClaims(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(6 * time.Hour)
+	repo := &models.Repository{Name: "my-app", MaxWorktrees: 1}
+
+	rejectedAt := func(at time.Time) *models.Claim {
+		return &models.Claim{ClaimedAt: at, ReleasedAt: &at, Outcome: models.ClaimOutcomeRejected}
+	}
+
+	claims := []*models.Claim{
+		// Holds the only worktree from 1h to 3h
+		claimAt(since.Add(1*time.Hour), 2*time.Hour, 100*time.Millisecond, models.ClaimOutcomeReleased),
+		// Turned away at 2h, when serving it would have taken a second worktree
+		rejectedAt(since.Add(2 * time.Hour)),
+		// Before the window
+		rejectedAt(since.Add(-1 * time.Hour)),
+	}
+
+	report := Compute(repo, claims, since, until)
+
+	if report.Claims != 1 {
+		t.Errorf("expected 1 claim, got %d", report.Claims)
+	}
+
+	if report.RejectedClaims != 1 {
+		t.Errorf("expected 1 rejected claim, got %d", report.RejectedClaims)
+	}
+
+	if report.PeakConcurrent != 1 {
+		t.Errorf("expected peak of 1 concurrent claim, got %d", report.PeakConcurrent)
+	}
+
+	// Only the time the worktree was actually held counts
+	if report.TimeAtCapacity != 2*time.Hour {
+		t.Errorf("expected 2h at capacity, got %s", report.TimeAtCapacity)
+	}
+
+	if report.LeaseP50 != 2*time.Hour {
+		t.Errorf("expected median lease 2h, got %s", report.LeaseP50)
+	}
+
+	if report.RecommendedSize != 3 {
+		t.Errorf("expected recommended size 3, got %d", report.RecommendedSize)
+	}
+}
@@ -0,0 +1,5 @@
+package version
+
+// Version is the gitpool release version. It is set at build time with
+// -ldflags "-X github.com/albertywu/gitpool/internal/version.Version=v1.2.3".
+var Version = "dev"